@@ -1,18 +1,22 @@
 package oauth
 
 import (
-	"crypto"
+	"context"
+	"crypto/ecdsa"
 	"crypto/rsa"
-	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
-	"io"
 	"math/big"
 	"net/http"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
 )
 
 // Constants for Apple URLs
@@ -21,11 +25,13 @@ const (
 	AppleURLAuthKeys   = AppleBaseEndpoint + "/auth/keys"
 	AppleURLAuthToken  = AppleBaseEndpoint + "/auth/token"
 	AppleURLAuthRevoke = AppleBaseEndpoint + "/auth/revoke"
+	AppleURLAuthorize  = AppleBaseEndpoint + "/auth/authorize"
 )
 
 // Apple struct represents the Apple OAuth provider.
 type Apple struct {
 	service *Service
+	keys    *jwks.Client
 }
 
 // AppleClaims struct represents the claims in Apple Identity Token.
@@ -59,6 +65,9 @@ type AppleClaims struct {
 
 	// Indicates if nonce is supported
 	NonceSupported bool `json:"nonce_supported"`
+
+	// Nonce sent in the authorization request
+	Nonce string `json:"nonce"`
 }
 
 // ApplePublicKey struct represents the public key used for signature verification.
@@ -87,169 +96,242 @@ type ApplePublicKeyResponse struct {
 	Keys []*ApplePublicKey `json:"keys"` // List of Apple public keys
 }
 
-// NewApple creates a new instance of the Apple OAuth provider.
-func NewApple(service *Service) *Apple {
-	service.Endpoint = AppleBaseEndpoint
-	return &Apple{service: service}
+// AppleTokenResponse struct represents the response of the Apple token endpoint.
+type AppleTokenResponse struct {
+	// Access token used to call Apple services
+	AccessToken string `json:"access_token"`
+
+	// Type of the access token, always "bearer"
+	TokenType string `json:"token_type"`
+
+	// Lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expires_in"`
+
+	// Refresh token used to obtain new access tokens
+	RefreshToken string `json:"refresh_token"`
+
+	// Identity Token of the user
+	IdToken string `json:"id_token"`
 }
 
-// getPublicKey retrieves the Apple public keys.
-func (p *Apple) getPublicKey() (ApplePublicKeyResponse, error) {
-	resp, err := New(AppleURLAuthKeys, http.MethodGet, p.service.ProxyURL, WithTimeout(30*time.Second)).Do()
-	if nil != err {
-		return ApplePublicKeyResponse{}, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return ApplePublicKeyResponse{}, fmt.Errorf("the status code is: %d", resp.StatusCode)
-	}
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ApplePublicKeyResponse{}, err
-	}
-	var value ApplePublicKeyResponse
-	if err = json.Unmarshal(data, &value); err != nil {
-		return ApplePublicKeyResponse{}, err
+// NewApple creates a new instance of the Apple OAuth provider.
+// URLs are derived from the Endpoint of the service, AppleBaseEndpoint by default.
+func NewApple(service *Service) *Apple {
+	if service.Endpoint == "" {
+		service.Endpoint = AppleBaseEndpoint
 	}
+	return &Apple{service: service, keys: service.keys(AuthApple, service.url(EndpointKeys, AppleBaseEndpoint, AppleURLAuthKeys))}
+}
 
-	return value, nil
+// url returns the URL of the named endpoint, fallback being its default.
+func (p *Apple) url(name, fallback string) string {
+	return p.service.url(name, AppleBaseEndpoint, fallback)
 }
 
-// decodePayload decodes the payload of the Identity Token.
-func (p *Apple) decodePayload(str string) (*AppleClaims, error) {
-	payload, err := base64.RawURLEncoding.DecodeString(str)
+// PublicKey converts the JWK modulus and exponent into an RSA public key.
+func (k *ApplePublicKey) PublicKey() (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
 	if err != nil {
-		return nil, fmt.Errorf("failed to base64url decode ID Token: %s", err.Error())
+		return nil, err
 	}
-	var claims *AppleClaims
-	err = json.Unmarshal(payload, &claims)
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal ID Token claims: %s", err.Error())
+		return nil, err
 	}
-	return claims, nil
+	return &rsa.PublicKey{
+		N: big.NewInt(0).SetBytes(nBytes),
+		E: int(big.NewInt(0).SetBytes(eBytes).Int64()),
+	}, nil
 }
 
 // VerifySignature verifies the signature of the Identity Token.
 func (p *Apple) VerifySignature(val []string) error {
-	// Step 1: Get the public key
-	keys, err := p.getPublicKey()
+	token, err := jwt.Parse(strings.Join(val, "."))
 	if err != nil {
-		return err
+		return &TokenError{Provider: AuthApple, Kind: ErrInvalidIdToken, Err: err}
 	}
-
-	// Step 2: Extract the encryption algorithm from the header
-	headerBytes, err := base64.RawURLEncoding.DecodeString(val[0])
+	key, err := keyFunc(AuthApple, p.keys)(context.Background(), token.Header)
 	if err != nil {
 		return err
 	}
-	var header struct {
-		Alg string `json:"alg"` // Encryption algorithm
-		Kid string `json:"kid"` // Key ID
-	}
-	if err = json.Unmarshal(headerBytes, &header); err != nil {
-		return err
+	if err = token.VerifySignature(key); err != nil {
+		return &TokenError{Provider: AuthApple, Kind: ErrInvalidSignature, Err: err}
 	}
+	return nil
+}
 
-	// Step 3: Find the matching public key in the collection
-	publicKey := &ApplePublicKey{}
-	for _, key := range keys.Keys {
-		if key.Alg == header.Alg && key.Kid == header.Kid {
-			publicKey = key
-			break
-		}
+// AuthCodeURL builds the Sign in with Apple authorization URL.
+// The name and email scopes are requested by default, which Apple only returns with the form_post response mode.
+//
+// documentation https://developer.apple.com/documentation/sign_in_with_apple/request_an_authorization_to_the_sign_in_with_apple_server
+func (p *Apple) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.url(EndpointAuthorize, AppleURLAuthorize), authDefaults{
+		scopes:       []string{"name", "email"},
+		nonce:        true,
+		responseMode: ResponseModeFormPost,
+	}, options...)
+}
+
+// IdToken verifies the Apple Identity Token: its RS256 signature, issuer, audience and expiry.
+func (p *Apple) IdToken(token string) (*AppleClaims, error) {
+	if token == "" {
+		return nil, ErrInvalidIdToken
 	}
-	// No matching public key found
-	if publicKey.Kid == "" {
-		return ErrInvalidSignature
+	verifier := &jwt.Verifier{
+		Algorithms: []string{jwt.RS256},
+		Keys:       keyFunc(AuthApple, p.keys),
+		Issuers:    []string{AppleBaseEndpoint},
+		Audiences:  p.service.audiences(),
 	}
-
-	// Step 4: Verify the signature using the public key
-	data := val[0] + "." + val[1]
-	signature, err := base64.RawURLEncoding.DecodeString(val[2])
-	if err != nil {
-		return err
+	claims := &AppleClaims{}
+	if _, err := verifier.Verify(context.Background(), token, claims); err != nil {
+		return nil, tokenError(AuthApple, err)
 	}
+	return claims, nil
+}
 
-	nBytes, err := base64.RawURLEncoding.DecodeString(publicKey.N)
+// Identify verifies the ID token of a code exchange and returns the user it identifies.
+// Apple only shares the name of the user in the first callback, it is not part of the identity.
+func (p *Apple) Identify(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	claims, err := p.IdToken(token.IdToken)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	eBytes, err := base64.RawURLEncoding.DecodeString(publicKey.E)
-	if err != nil {
-		return err
+	if err = checkNonce(AuthApple, nonce, claims.Nonce); err != nil {
+		return nil, err
 	}
-
-	pubKey := &rsa.PublicKey{
-		N: big.NewInt(0).SetBytes(nBytes),
-		E: int(big.NewInt(0).SetBytes(eBytes).Int64()),
+	identity := &Identity{
+		Provider:      AuthApple,
+		Subject:       claims.Sub,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == "true",
 	}
+	if parsed, err := jwt.Parse(token.IdToken); err == nil {
+		identity.Raw = rawClaims(parsed.Payload)
+	}
+	return identity, nil
+}
 
-	hashed := sha256.Sum256([]byte(data))
-	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, hashed[:], signature); nil != err {
-		return err
+// Exchange exchanges an authorization code for tokens.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+//
+// documentation https://developer.apple.com/documentation/sign_in_with_apple/generate_and_validate_tokens
+func (p *Apple) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
 	}
+	// The redirect_uri parameter must be provided when verifying the Identity Code,
+	// and it must use the HTTPS protocol.
+	return p.service.token(ctx, AuthApple, p.url(EndpointToken, AppleURLAuthToken), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
+}
 
-	return nil
+// Refresh validates a refresh token and gets a new access token and ID token.
+// Apple does not rotate refresh tokens, the given one is kept in the result.
+//
+// documentation https://developer.apple.com/documentation/sign_in_with_apple/generate_and_validate_tokens
+func (p *Apple) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, AuthApple, p.url(EndpointToken, AppleURLAuthToken), AuthStylePost, refreshToken)
 }
 
-// IdToken verifies the Apple Identity Token.
-func (p *Apple) IdToken(token string) (*AppleClaims, error) {
-	if token == "" {
-		return nil, ErrInvalidIdToken
+// IdentityCode verifies the Apple Identity Code, with the PKCE verifier when one was used.
+func (p *Apple) IdentityCode(code string, codeVerifier ...string) (int, error) {
+	if code == "" {
+		return -1, ErrInvalidIdCode
 	}
-	// Split the token into header, payload, and signature (arr[0], arr[1], arr[2])
-	arr := strings.Split(token, ".")
-	if err := p.VerifySignature(arr); nil != err {
-		return nil, err
+	var verifier string
+	if len(codeVerifier) > 0 {
+		verifier = codeVerifier[0]
 	}
-	claims, err := p.decodePayload(arr[1])
-	if err != nil {
-		return nil, err
+	if _, err := p.Exchange(context.Background(), code, verifier); err != nil {
+		var providerErr *ProviderError
+		if errors.As(err, &providerErr) {
+			return providerErr.StatusCode, err
+		}
+		return -1, err
 	}
-	return claims, nil
+	return http.StatusOK, nil
 }
 
-// IdentityCode verifies the Apple Identity Code.
-func (p *Apple) IdentityCode(code string) (int, error) {
-	if code == "" {
-		return -1, ErrInvalidIdCode
+// AppleClientSecretTTL is the lifetime of the client secrets created by AppleClientSecret, close to the 6 months allowed by Apple.
+const AppleClientSecretTTL = 180 * 24 * time.Hour
+
+// AppleClientSecret creates the client secret of a Sign in with Apple service: a JWT signed with ES256 by the
+// private key keyID of the team teamID. It must be renewed before it expires, after ttl.
+//
+// documentation https://developer.apple.com/documentation/accountorganizationaldatasharing/creating-a-client-secret
+func AppleClientSecret(teamID, keyID, clientID string, key *ecdsa.PrivateKey, ttl time.Duration) (string, error) {
+	return appleClientSecret(teamID, keyID, clientID, key, time.Now(), ttl)
+}
+
+// appleClientSecret creates a client secret issued at now.
+func appleClientSecret(teamID, keyID, clientID string, key *ecdsa.PrivateKey, now time.Time, ttl time.Duration) (string, error) {
+	if teamID == "" || keyID == "" || key == nil {
+		return "", ErrInvalidClientSecret
 	}
-	// The redirect_uri parameter must be provided when verifying the Identity Code,
-	// and it must use the HTTPS protocol.
-	// if uri := strings.ToLower(o.RedirectUri); strings.HasPrefix(uri, "https://") {
-	// 	return nil, ErrInvalidRedirectURI
-	//}
-	params := url.Values{
-		"client_id":     []string{p.service.ClientID},
-		"client_secret": []string{p.service.ClientSecret},
-		"code":          []string{code},
-		"grant_type":    []string{"authorization_code"},
-		"redirect_uri":  []string{p.service.RedirectURL},
-	}
-	header := http.Header{
-		"Content-Type": []string{"application/x-www-form-urlencoded"},
-	}
-	resp, err := New(AppleURLAuthToken, http.MethodPost, p.service.ProxyURL,
-		WithTimeout(30*time.Second),
-		WithHeader(header),
-		WithData(params),
-	).Post()
-	if err != nil {
-		return -1, err
+	if ttl <= 0 || ttl > AppleClientSecretTTL {
+		ttl = AppleClientSecretTTL
 	}
+	return jwt.Sign(jwt.ES256, keyID, &jwt.Claims{
+		Issuer:    teamID,
+		Subject:   clientID,
+		Audience:  jwt.Audience{AppleBaseEndpoint},
+		IssuedAt:  jwt.NumericDate(now.Unix()),
+		ExpiresAt: jwt.NumericDate(now.Add(ttl).Unix()),
+	}, key)
+}
 
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return resp.StatusCode, fmt.Errorf("the status code is: %d", resp.StatusCode)
+// AppleSecretRenewal is how long before expiry an AppleSecret creates a new client secret.
+const AppleSecretRenewal = 7 * 24 * time.Hour
+
+// AppleSecret creates the client secret of a Sign in with Apple service and renews it before it expires,
+// so that long-running servers keep exchanging codes. Use it with WithAppleSecret.
+type AppleSecret struct {
+	teamID   string
+	keyID    string
+	clientID string
+	key      *ecdsa.PrivateKey
+	now      func() time.Time
+
+	mu     sync.Mutex
+	secret string
+	expiry time.Time
+}
+
+// NewAppleSecret creates an AppleSecret signing with the private key keyID of the team teamID.
+func NewAppleSecret(teamID, keyID, clientID string, key *ecdsa.PrivateKey) *AppleSecret {
+	return &AppleSecret{teamID: teamID, keyID: keyID, clientID: clientID, key: key, now: time.Now}
+}
+
+// Secret returns the current client secret, creating a new one when it expires within AppleSecretRenewal.
+func (s *AppleSecret) Secret() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.secret != "" && now.Add(AppleSecretRenewal).Before(s.expiry) {
+		return s.secret, nil
 	}
-	data, err := io.ReadAll(resp.Body)
+	secret, err := appleClientSecret(s.teamID, s.keyID, s.clientID, s.key, now, AppleClientSecretTTL)
 	if err != nil {
-		return -1, err
+		return "", err
 	}
+	s.secret, s.expiry = secret, now.Add(AppleClientSecretTTL)
+	return secret, nil
+}
 
-	var result map[string]interface{}
-	if err = json.Unmarshal(data, &result); err != nil {
-		return -1, err
+// ParseApplePrivateKey parses the PKCS #8 PEM private key (.p8 file) downloaded from the Apple developer account.
+func ParseApplePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM private key", ErrInvalidClientSecret)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientSecret, err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected an ECDSA private key", ErrInvalidClientSecret)
 	}
-	return result["code"].(int), nil
+	return ecKey, nil
 }
@@ -0,0 +1,217 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ResponseMode is how the provider returns the authorization response to the RedirectURL.
+type ResponseMode string
+
+const (
+	ResponseModeQuery    ResponseMode = "query"
+	ResponseModeFormPost ResponseMode = "form_post"
+	ResponseModeFragment ResponseMode = "fragment"
+)
+
+// AuthRequest is the result of building an authorization URL.
+// State and Nonce must be persisted until the callback to validate the response.
+type AuthRequest struct {
+	// URL the user is redirected to
+	URL string
+
+	// State Random value echoed back in the callback, protecting against CSRF
+	State string
+
+	// Nonce Random value echoed back in the ID token, protecting against replay. Empty for providers without ID tokens.
+	Nonce string
+
+	// CodeVerifier PKCE verifier to send with the code exchange, set when WithPKCE is used
+	CodeVerifier string
+}
+
+// authParams collects the options of an authorization request.
+type authParams struct {
+	scopes       []string
+	prompt       string
+	loginHint    string
+	responseMode ResponseMode
+	state        string
+	nonce        string
+	pkce         bool
+	codeVerifier string
+	extra        url.Values
+}
+
+// AuthOption customizes an authorization URL.
+type AuthOption func(*authParams)
+
+// WithScopes replaces the default scopes of the provider.
+func WithScopes(scopes ...string) AuthOption {
+	return func(params *authParams) {
+		params.scopes = scopes
+	}
+}
+
+// WithPrompt sets the prompt parameter, e.g. "consent" or "select_account".
+func WithPrompt(prompt string) AuthOption {
+	return func(params *authParams) {
+		params.prompt = prompt
+	}
+}
+
+// WithLoginHint sets the login_hint parameter, usually an email address.
+func WithLoginHint(hint string) AuthOption {
+	return func(params *authParams) {
+		params.loginHint = hint
+	}
+}
+
+// WithResponseMode sets the response_mode parameter.
+func WithResponseMode(mode ResponseMode) AuthOption {
+	return func(params *authParams) {
+		params.responseMode = mode
+	}
+}
+
+// WithState uses the given state instead of a random one.
+func WithState(state string) AuthOption {
+	return func(params *authParams) {
+		params.state = state
+	}
+}
+
+// WithNonce uses the given nonce instead of a random one.
+func WithNonce(nonce string) AuthOption {
+	return func(params *authParams) {
+		params.nonce = nonce
+	}
+}
+
+// reservedAuthParams are set by the authorization request itself and cannot be given with WithAuthParam,
+// the state, nonce and PKCE parameters protecting the callback.
+var reservedAuthParams = map[string]bool{
+	"response_type":         true,
+	"client_id":             true,
+	"redirect_uri":          true,
+	"state":                 true,
+	"nonce":                 true,
+	"code_challenge":        true,
+	"code_challenge_method": true,
+}
+
+// WithAuthParam adds an extra query parameter, e.g. access_type=offline for Google.
+// The parameters set by the request, such as state, nonce or redirect_uri, are rejected by AuthCodeURL:
+// use WithState, WithNonce or WithPKCE instead.
+func WithAuthParam(key, value string) AuthOption {
+	return func(params *authParams) {
+		if params.extra == nil {
+			params.extra = url.Values{}
+		}
+		params.extra.Set(key, value)
+	}
+}
+
+// authDefaults are the provider specific defaults of an authorization request.
+type authDefaults struct {
+	// scopes requested when WithScopes is not used
+	scopes []string
+
+	// separator joining the scopes, a space unless the provider expects otherwise
+	separator string
+
+	// nonce is set for OpenID Connect providers
+	nonce bool
+
+	// responseMode used when WithResponseMode is not used
+	responseMode ResponseMode
+}
+
+// authCodeURL builds the authorization URL of the code flow for the given endpoint.
+func (s *Service) authCodeURL(endpoint string, defaults authDefaults, options ...AuthOption) (*AuthRequest, error) {
+	if s.RedirectURL == "" {
+		return nil, ErrInvalidRedirectURL
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, &RequestError{Op: "authorize", URL: endpoint, Kind: ErrInvalidRequestURL, Err: err}
+	}
+
+	params := &authParams{scopes: defaults.scopes, responseMode: defaults.responseMode}
+	for _, option := range options {
+		option(params)
+	}
+	for key := range params.extra {
+		if reservedAuthParams[key] {
+			return nil, &RequestError{Op: "authorize", URL: endpoint, Kind: ErrInvalidRequestURL, Err: fmt.Errorf("parameter %q is set by the request", key)}
+		}
+	}
+
+	request := &AuthRequest{State: params.state, Nonce: params.nonce}
+	if request.State == "" {
+		if request.State, err = randomString(32); err != nil {
+			return nil, err
+		}
+	}
+	if request.Nonce == "" && defaults.nonce {
+		if request.Nonce, err = randomString(32); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.pkce {
+		request.CodeVerifier = params.codeVerifier
+		if request.CodeVerifier == "" {
+			if request.CodeVerifier, err = randomString(32); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", s.ClientID)
+	query.Set("redirect_uri", s.RedirectURL)
+	query.Set("state", request.State)
+	if len(params.scopes) > 0 {
+		separator := defaults.separator
+		if separator == "" {
+			separator = " "
+		}
+		query.Set("scope", strings.Join(params.scopes, separator))
+	}
+	if request.Nonce != "" {
+		query.Set("nonce", request.Nonce)
+	}
+	if params.prompt != "" {
+		query.Set("prompt", params.prompt)
+	}
+	if params.loginHint != "" {
+		query.Set("login_hint", params.loginHint)
+	}
+	if request.CodeVerifier != "" {
+		query.Set("code_challenge", CodeChallenge(request.CodeVerifier))
+		query.Set("code_challenge_method", PKCEMethodS256)
+	}
+	if params.responseMode != "" {
+		query.Set("response_mode", string(params.responseMode))
+	}
+	for key, values := range params.extra {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	request.URL = u.String()
+	return request, nil
+}
+
+// randomString returns n cryptographically random bytes encoded with unpadded base64url.
+func randomString(n int) (string, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAuthCodeURL(t *testing.T) {
+	service, err := NewService("com.short.roll", "secret", AuthApple, WithRedirectURL("https://example.com/callback/apple"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	request, err := NewApple(service).AuthCodeURL(WithLoginHint("rabbit@icloud.com"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(request.URL)
+	query := u.Query()
+	if u.Host != "appleid.apple.com" || query.Get("response_mode") != "form_post" || query.Get("scope") != "name email" ||
+		query.Get("state") != request.State || query.Get("nonce") != request.Nonce || query.Get("login_hint") != "rabbit@icloud.com" ||
+		query.Get("redirect_uri") != "https://example.com/callback/apple" || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization url %s", request.URL)
+	}
+	if len(request.State) < 32 || request.State == request.Nonce {
+		t.Fatalf("expected distinct random state and nonce, got %q %q", request.State, request.Nonce)
+	}
+
+	service, _ = NewService("app-id", "secret", AuthFacebook, WithRedirectURL("https://example.com/callback/facebook"))
+	request, err = NewFacebook(service).AuthCodeURL(WithScopes("email", "user_birthday"), WithState("fixed"), WithAuthParam("display", "popup"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	u, _ = url.Parse(request.URL)
+	query = u.Query()
+	if query.Get("scope") != "email,user_birthday" || query.Get("state") != "fixed" || query.Get("display") != "popup" || query.Has("nonce") {
+		t.Fatalf("unexpected authorization url %s", request.URL)
+	}
+
+	for _, key := range []string{"state", "nonce", "client_id", "redirect_uri", "response_type", "code_challenge", "code_challenge_method"} {
+		if _, err = NewFacebook(service).AuthCodeURL(WithAuthParam(key, "evil")); !errors.Is(err, ErrInvalidRequestURL) {
+			t.Fatalf("expected ErrInvalidRequestURL overriding %s, got %v", key, err)
+		}
+	}
+
+	service, _ = NewService("client", "secret", AuthGoogle)
+	if _, err = NewGoogle(service).AuthCodeURL(); !errors.Is(err, ErrInvalidRedirectURL) {
+		t.Fatalf("expected ErrInvalidRedirectURL, got %v", err)
+	}
+}
+
+func TestPKCE(t *testing.T) {
+	var challenge string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.URL.Path != "/token" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"code_verifier mismatch"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","token_type":"Bearer","expires_in":3599}`))
+	}))
+	defer server.Close()
+
+	service, err := NewService("client", "secret", AuthGoogle, WithRedirectURL("https://example.com/callback/google"), WithTransport(rewriteTransport{server.URL}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	google := NewGoogle(service)
+	request, err := google.AuthCodeURL(WithPKCE())
+	if nil != err {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(request.URL)
+	challenge = u.Query().Get("code_challenge")
+	if len(request.CodeVerifier) < 43 || challenge == "" || u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization url %s", request.URL)
+	}
+
+	ctx := context.Background()
+	if _, err = google.Exchange(ctx, "code", "wrong"); !errors.Is(err, ErrInvalidIdCode) {
+		t.Fatalf("expected ErrInvalidIdCode, got %v", err)
+	}
+	token, err := google.Exchange(ctx, "code", request.CodeVerifier)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access" || token.Expiry.IsZero() {
+		t.Fatalf("unexpected token %+v", token)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	oauth "socialgoauth.org/social-goauth"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+// decodedToken is the output of decode.
+type decodedToken struct {
+	Header jwt.Header             `json:"header"`
+	Claims map[string]interface{} `json:"claims"`
+
+	// Times Registered time claims as RFC 3339 dates
+	Times map[string]string `json:"times,omitempty"`
+
+	// Expired Reports whether the exp claim is in the past
+	Expired bool `json:"expired"`
+}
+
+// decode prints the header and claims of a token without verifying it.
+func decode(_ context.Context, env *environment, args []string) error {
+	args, err := parse(env, "decode", flag.NewFlagSet("decode", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	token, err := jwt.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	decoded := &decodedToken{Header: token.Header, Times: map[string]string{}}
+	if err = json.Unmarshal(token.Payload, &decoded.Claims); err != nil {
+		return fmt.Errorf("%w: %v", jwt.ErrMalformed, err)
+	}
+	for _, claim := range []string{"exp", "iat", "nbf", "auth_time"} {
+		if seconds, ok := decoded.Claims[claim].(float64); ok {
+			at := time.Unix(int64(seconds), 0)
+			decoded.Times[claim] = at.Format(time.RFC3339)
+			if claim == "exp" {
+				decoded.Expired = time.Now().After(at)
+			}
+		}
+	}
+	return env.print(decoded)
+}
+
+// verify verifies an ID token with the keys of the provider and prints its identity.
+func verify(ctx context.Context, env *environment, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	nonce := flags.String("nonce", "", "nonce of the authorization request, checked when set")
+	args, err := parse(env, "verify", flags, args, 1)
+	if err != nil {
+		return err
+	}
+	provider, _, _, err := env.loginProvider(ctx)
+	if err != nil {
+		return err
+	}
+	identity, err := provider.Identify(ctx, &oauth.Token{IdToken: args[0]}, *nonce)
+	if err != nil {
+		return err
+	}
+	return env.print(identity)
+}
+
+// exchange exchanges an authorization code for tokens.
+func exchange(ctx context.Context, env *environment, args []string) error {
+	flags := flag.NewFlagSet("exchange", flag.ContinueOnError)
+	verifier := flags.String("verifier", "", "PKCE code verifier of the authorization request")
+	args, err := parse(env, "exchange", flags, args, 1)
+	if err != nil {
+		return err
+	}
+	provider, _, _, err := env.loginProvider(ctx)
+	if err != nil {
+		return err
+	}
+	token, err := provider.Exchange(ctx, args[0], *verifier)
+	if err != nil {
+		return err
+	}
+	return env.print(token)
+}
+
+// refresh gets new tokens with a refresh token.
+func refresh(ctx context.Context, env *environment, args []string) error {
+	args, err := parse(env, "refresh", flag.NewFlagSet("refresh", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	provider, _, _, err := env.loginProvider(ctx)
+	if err != nil {
+		return err
+	}
+	refresher, ok := provider.(oauth.Refresher)
+	if !ok {
+		return oauth.ErrNotSupported
+	}
+	token, err := refresher.Refresh(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return env.print(token)
+}
+
+// revoke revokes an access or refresh token.
+func revoke(ctx context.Context, env *environment, args []string) error {
+	args, err := parse(env, "revoke", flag.NewFlagSet("revoke", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	provider, _, _, err := env.loginProvider(ctx)
+	if err != nil {
+		return err
+	}
+	switch p := provider.(type) {
+	case *oauth.Line:
+		_, err = p.RevokeAccessToken(args[0])
+	case *oauth.Kakao:
+		_, err = p.Logout(ctx, args[0])
+	case interface {
+		Revoke(ctx context.Context, token string) error
+	}:
+		err = p.Revoke(ctx, args[0])
+	default:
+		err = oauth.ErrNotSupported
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout, "revoked")
+	return nil
+}
+
+// profile prints the profile of the user: the LINE profile, the Microsoft Graph user, the Kakao user, or the identity read
+// with the access token, e.g. the Facebook or GitHub user or the userinfo of the provider.
+func profile(ctx context.Context, env *environment, args []string) error {
+	args, err := parse(env, "profile", flag.NewFlagSet("profile", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	provider, _, _, err := env.loginProvider(ctx)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	switch p := provider.(type) {
+	case *oauth.Line:
+		value, err = p.UserProfile(args[0])
+	case *oauth.Microsoft:
+		value, err = p.Me(ctx, args[0])
+	case *oauth.Kakao:
+		value, err = p.UserMe(ctx, args[0])
+	default:
+		value, err = provider.Identify(ctx, &oauth.Token{AccessToken: args[0]}, "")
+	}
+	if err != nil {
+		return err
+	}
+	return env.print(value)
+}
+
+// appleSecret generates the client secret JWT of Sign in with Apple from a .p8 private key.
+func appleSecret(_ context.Context, env *environment, args []string) error {
+	flags := flag.NewFlagSet("apple-secret", flag.ContinueOnError)
+	teamID := flags.String("team-id", "", "Apple developer team ID")
+	keyID := flags.String("key-id", "", "ID of the Sign in with Apple private key")
+	clientID := flags.String("client-id", "", "Services ID or bundle ID of the application")
+	keyFile := flags.String("key", "", "`file` of the private key (.p8)")
+	ttl := flags.Duration("ttl", oauth.AppleClientSecretTTL, "lifetime of the secret, at most 6 months")
+	if _, err := parse(env, "apple-secret", flags, args, 0); err != nil {
+		return err
+	}
+	if *teamID == "" || *keyID == "" || *clientID == "" || *keyFile == "" {
+		flags.Usage()
+		return errUsage
+	}
+	data, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	key, err := oauth.ParseApplePrivateKey(data)
+	if err != nil {
+		return err
+	}
+	secret, err := oauth.AppleClientSecret(*teamID, *keyID, *clientID, key, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout, secret)
+	return nil
+}
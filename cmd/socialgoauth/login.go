@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	oauth "socialgoauth.org/social-goauth"
+)
+
+// loginResult is the output of login.
+type loginResult struct {
+	Identity *oauth.Identity `json:"identity"`
+	Token    *oauth.Token    `json:"token"`
+}
+
+// login runs a local server on the redirect URL of the provider: / starts the login and the callback
+// completes it. The identity and tokens of the first login are printed.
+func login(ctx context.Context, env *environment, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	listen := flags.String("listen", "", "`address` of the local server (default: host of the redirect URL)")
+	timeout := flags.Duration("timeout", 5*time.Minute, "time allowed to complete the login")
+	pkce := flags.Bool("pkce", true, "send a PKCE code challenge")
+	if _, err := parse(env, "login", flags, args, 0); err != nil {
+		return err
+	}
+	provider, config, authOptions, err := env.loginProvider(ctx)
+	if err != nil {
+		return err
+	}
+	redirect, err := url.Parse(config.RedirectURL)
+	if err != nil {
+		return err
+	}
+	if *listen == "" {
+		*listen = redirect.Host
+	}
+
+	results := make(chan *loginResult, 1)
+	failures := make(chan error, 1)
+	options := []oauth.HandlerOption{
+		oauth.WithAuthOptions(authOptions...),
+		oauth.WithOnSuccess(func(w http.ResponseWriter, _ *http.Request, identity *oauth.Identity, token *oauth.Token) {
+			fmt.Fprintln(w, "Logged in, you can close this window.")
+			select {
+			case results <- &loginResult{Identity: identity, Token: token}:
+			default:
+			}
+		}),
+		oauth.WithOnError(func(w http.ResponseWriter, _ *http.Request, err error) {
+			http.Error(w, "Login failed: "+err.Error(), http.StatusUnauthorized)
+			select {
+			case failures <- err:
+			default:
+			}
+		}),
+	}
+	if !*pkce {
+		options = append(options, oauth.WithoutPKCE())
+	}
+	handler := oauth.NewHandler(config.Name, provider, oauth.NewMemoryStateStore(*timeout, oauth.DefaultStateStoreSize), options...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		handler.Login(w, r)
+	})
+	mux.HandleFunc(redirect.Path, handler.Callback)
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			select {
+			case failures <- err:
+			default:
+			}
+		}
+	}()
+	defer func() {
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(env.stderr, "Open http://%s/ in a browser to log in with %s\n", listener.Addr(), config.Name)
+
+	timer := time.NewTimer(*timeout)
+	defer timer.Stop()
+	select {
+	case result := <-results:
+		return env.print(result)
+	case err = <-failures:
+		return err
+	case <-timer.C:
+		return errors.New("login timed out")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,205 @@
+// Command socialgoauth inspects and tests the social logins of an application.
+//
+// The providers are read from a configuration file (-config) in the format of oauth.LoadConfig, or from
+// the environment variables read by oauth.LoadConfigEnv with the OAUTH prefix.
+//
+// Usage:
+//
+//	socialgoauth [-config file] [-provider name] <command> [arguments]
+//
+// The commands are:
+//
+//	decode <jwt>                    print the header and claims of a token without verifying it
+//	verify [-nonce n] <id_token>    verify an ID token and print the identity it carries
+//	exchange [-verifier v] <code>   exchange an authorization code for tokens
+//	refresh <refresh_token>         get new tokens with a refresh token
+//	revoke <token>                  revoke an access or refresh token
+//	profile <access_token>          print the profile of the user (LINE, Microsoft Graph, Kakao, Facebook, GitHub, userinfo)
+//	apple-secret -team-id t -key-id k -client-id c -key file.p8 [-ttl d]
+//	                                generate the client secret JWT of Sign in with Apple
+//	login [-listen addr]            run a local callback server, complete a browser login and print the identity
+//
+// Codes and tokens may start with "-", e.g. base64url values: pass them after "--" so that they are not
+// read as flags, e.g. socialgoauth refresh -- -Xy7....
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	oauth "socialgoauth.org/social-goauth"
+)
+
+// EnvPrefix is the prefix of the environment variables configuring the providers without -config.
+const EnvPrefix = "OAUTH"
+
+var errUsage = errors.New("usage")
+
+// command is a subcommand of the tool.
+type command struct {
+	// usage Arguments of the command, printed by the usage
+	usage string
+
+	// run Runs the command with the arguments following its name
+	run func(ctx context.Context, env *environment, args []string) error
+}
+
+var commands map[string]command
+
+// init registers the commands, which print their own usage from this table.
+func init() {
+	commands = map[string]command{
+		"decode":       {"<jwt>", decode},
+		"verify":       {"[-nonce n] <id_token>", verify},
+		"exchange":     {"[-verifier v] <code>", exchange},
+		"refresh":      {"<refresh_token>", refresh},
+		"revoke":       {"<token>", revoke},
+		"profile":      {"<access_token>", profile},
+		"apple-secret": {"-team-id t -key-id k -client-id c -key file.p8 [-ttl d]", appleSecret},
+		"login":        {"[-listen addr] [-timeout d] [-pkce=false]", login},
+	}
+}
+
+// environment holds the global flags and the output of a run.
+type environment struct {
+	configPath string
+	provider   string
+	stdout     io.Writer
+	stderr     io.Writer
+
+	// options are added to the service of the provider, e.g. the HTTP client of the tests
+	options []oauth.Option
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := run(ctx, os.Args[1:], &environment{stdout: os.Stdout, stderr: os.Stderr})
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "socialgoauth:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags and runs the command.
+func run(ctx context.Context, args []string, env *environment) error {
+	flags := flag.NewFlagSet("socialgoauth", flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.StringVar(&env.configPath, "config", "", "configuration `file` of the providers, JSON or YAML (default: "+EnvPrefix+"_* environment variables)")
+	flags.StringVar(&env.provider, "provider", "", "`name` of the provider, optional when a single provider is configured")
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() == 0 {
+		usage(flags)
+		return errUsage
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(env.stderr, "unknown command %q\n", flags.Arg(0))
+		usage(flags)
+		return errUsage
+	}
+	return cmd.run(ctx, env, flags.Args()[1:])
+}
+
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintln(out, "usage: socialgoauth [-config file] [-provider name] <command> [arguments]")
+	fmt.Fprintln(out)
+	flags.PrintDefaults()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, `pass codes and tokens starting with "-" after "--", e.g. socialgoauth refresh -- <refresh_token>`)
+}
+
+// parse parses the flags of a command and checks its number of positional arguments.
+func parse(env *environment, name string, flags *flag.FlagSet, args []string, positional int) ([]string, error) {
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(env.stderr, "usage: socialgoauth %s %s\n", name, commands[name].usage)
+		if positional > 0 {
+			fmt.Fprintln(env.stderr, `pass values starting with "-" after "--"`)
+		}
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if flags.NArg() != positional {
+		flags.Usage()
+		return nil, errUsage
+	}
+	return flags.Args(), nil
+}
+
+// loadConfig reads the configuration file, or the environment variables without -config.
+func (env *environment) loadConfig() (*oauth.Config, error) {
+	if env.configPath == "" {
+		return oauth.LoadConfigEnv(EnvPrefix)
+	}
+	return oauth.LoadConfig(env.configPath)
+}
+
+// providerConfig returns the configuration of the selected provider.
+func (env *environment) providerConfig() (*oauth.ProviderConfig, error) {
+	config, err := env.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if env.provider == "" {
+		if len(config.Providers) != 1 {
+			return nil, fmt.Errorf("%d providers configured, select one with -provider", len(config.Providers))
+		}
+		return &config.Providers[0], nil
+	}
+	var names []string
+	for i := range config.Providers {
+		if config.Providers[i].Name == env.provider {
+			return &config.Providers[i], nil
+		}
+		names = append(names, config.Providers[i].Name)
+	}
+	return nil, fmt.Errorf("%w: %q, configured: %s", oauth.ErrProviderNotFound, env.provider, strings.Join(names, ", "))
+}
+
+// loginProvider builds the selected provider.
+func (env *environment) loginProvider(ctx context.Context) (oauth.LoginProvider, *oauth.ProviderConfig, []oauth.AuthOption, error) {
+	config, err := env.providerConfig()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	provider, authOptions, err := config.Build(ctx, env.options...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return provider, config, authOptions, nil
+}
+
+// print writes value as indented JSON.
+func (env *environment) print(value interface{}) error {
+	encoder := json.NewEncoder(env.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	oauth "socialgoauth.org/social-goauth"
+	"socialgoauth.org/social-goauth/jwt"
+	"socialgoauth.org/social-goauth/oauthtest"
+)
+
+// googleConfig writes the configuration of a Google provider served by server.
+func googleConfig(t *testing.T, server *oauthtest.Server, redirectURL string) string {
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	err := os.WriteFile(path, []byte(fmt.Sprintf(`
+providers:
+  - type: google
+    client_id: %s
+    client_secret: %s
+    redirect_url: %s
+    endpoint: %s
+`, server.ClientID, server.ClientSecret, redirectURL, server.URL)), 0o600)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runCommand(ctx context.Context, args ...string) (string, error) {
+	stdout := &bytes.Buffer{}
+	err := run(ctx, args, &environment{stdout: stdout, stderr: io.Discard})
+	return stdout.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	server := oauthtest.NewGoogle("web.apps.googleusercontent.com", "secret")
+	defer server.Close()
+	config := googleConfig(t, server, "https://example.com/callback/google")
+	ctx := context.Background()
+
+	idToken := server.IDToken("n-0S6_WzA2Mj", nil)
+	out, err := runCommand(ctx, "decode", idToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	decoded := &decodedToken{}
+	if err = json.Unmarshal([]byte(out), decoded); nil != err {
+		t.Fatal(err)
+	}
+	if decoded.Header.Alg != jwt.RS256 || decoded.Claims["sub"] != "000001.test" || decoded.Times["exp"] == "" || decoded.Expired {
+		t.Fatalf("unexpected decoded token %s", out)
+	}
+
+	out, err = runCommand(ctx, "-config", config, "verify", "-nonce", "n-0S6_WzA2Mj", "--", idToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	identity := &oauth.Identity{}
+	if err = json.Unmarshal([]byte(out), identity); nil != err {
+		t.Fatal(err)
+	}
+	if identity.Provider != oauth.AuthGoogle || identity.Subject != "000001.test" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	if _, err = runCommand(ctx, "-config", config, "verify", "-nonce", "other", "--", idToken); !errors.Is(err, oauth.ErrInvalidIdToken) {
+		t.Fatalf("expected ErrInvalidIdToken, got %v", err)
+	}
+
+	code := server.Code("https://example.com/callback/google", "", "")
+	out, err = runCommand(ctx, "-config", config, "-provider", "Google", "exchange", "--", code)
+	if nil != err {
+		t.Fatal(err)
+	}
+	token := &oauth.Token{}
+	if err = json.Unmarshal([]byte(out), token); nil != err {
+		t.Fatal(err)
+	}
+	if token.AccessToken == "" || token.RefreshToken == "" {
+		t.Fatalf("unexpected token %s", out)
+	}
+	if out, err = runCommand(ctx, "-config", config, "refresh", "--", token.RefreshToken); nil != err || !strings.Contains(out, "access_token") {
+		t.Fatalf("unexpected refresh %s: %v", out, err)
+	}
+
+	if _, err = runCommand(ctx, "-config", config, "refresh", "--", "-unknown"); !errors.Is(err, oauth.ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken for a token starting with -, got %v", err)
+	}
+	if _, err = runCommand(ctx, "-config", config, "-provider", "apple", "refresh", "x"); !errors.Is(err, oauth.ErrProviderNotFound) {
+		t.Fatalf("expected ErrProviderNotFound, got %v", err)
+	}
+	if _, err = runCommand(ctx, "unknown"); !errors.Is(err, errUsage) {
+		t.Fatalf("expected a usage error, got %v", err)
+	}
+	if _, err = runCommand(ctx, "decode"); !errors.Is(err, errUsage) {
+		t.Fatalf("expected a usage error, got %v", err)
+	}
+}
+
+func TestAppleSecret(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	path := filepath.Join(t.TempDir(), "AuthKey.p8")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); nil != err {
+		t.Fatal(err)
+	}
+	out, err := runCommand(context.Background(), "apple-secret", "-team-id", "TEAM123456", "-key-id", "KEY1234567", "-client-id", "com.short.roll", "-key", path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	verifier := &jwt.Verifier{Algorithms: []string{jwt.ES256}, Keys: jwt.StaticKey(&key.PublicKey), Issuers: []string{"TEAM123456"}, Audiences: []string{oauth.AppleBaseEndpoint}}
+	claims := &jwt.Claims{}
+	if _, err = verifier.Verify(context.Background(), strings.TrimSpace(out), claims); nil != err {
+		t.Fatal(err)
+	}
+	if claims.Subject != "com.short.roll" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	server := oauthtest.NewGoogle("web.apps.googleusercontent.com", "secret")
+	defer server.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	config := googleConfig(t, server, "http://"+addr+"/callback/google")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	stdout := &bytes.Buffer{}
+	go func() {
+		done <- run(ctx, []string{"-config", config, "login"}, &environment{stdout: stdout, stderr: io.Discard})
+	}()
+
+	var resp *http.Response
+	for resp == nil {
+		if resp, err = http.Get("http://" + addr + "/"); err != nil {
+			select {
+			case err = <-done:
+				t.Fatal(err)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected callback response %d %s", resp.StatusCode, body)
+	}
+	if err = <-done; nil != err {
+		t.Fatal(err)
+	}
+	result := &loginResult{}
+	if err = json.Unmarshal(stdout.Bytes(), result); nil != err {
+		t.Fatal(err)
+	}
+	if result.Identity.Subject != "000001.test" || result.Token.AccessToken == "" {
+		t.Fatalf("unexpected login %s", stdout)
+	}
+}
@@ -0,0 +1,508 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat is the encoding of a configuration file.
+type ConfigFormat string
+
+const (
+	ConfigJSON ConfigFormat = "json"
+	ConfigYAML ConfigFormat = "yaml"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// ConfigError is a validation error of a configuration, naming the offending field,
+// e.g. "providers[google].client_id".
+type ConfigError struct {
+	// Field Path of the offending field
+	Field string
+
+	// Err What is wrong with the field
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config %s: %v", e.Field, e.Err)
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Config is the configuration of a set of providers.
+type Config struct {
+	// Providers Configured providers, in the order of the login page
+	Providers []ProviderConfig `json:"providers" yaml:"providers"`
+}
+
+// ProviderConfig is the configuration of a provider.
+// Secrets can be given inline or read from a file with the *_file fields.
+type ProviderConfig struct {
+	// Name Unique name of the provider, the type by default
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Type Kind of provider: Google, Apple, Facebook, Line, GitHub, Microsoft, Kakao, OIDC or OAuth2, case insensitive
+	Type AuthType `json:"type" yaml:"type"`
+
+	// ClientID OAuth client ID, the Services ID for Apple and the Channel ID for LINE
+	ClientID string `json:"client_id" yaml:"client_id"`
+
+	// ClientSecret OAuth client secret, the Channel secret for LINE. Generated from the private key and renewed for Apple.
+	ClientSecret string `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+
+	// ClientSecretFile File containing the client secret
+	ClientSecretFile string `json:"client_secret_file,omitempty" yaml:"client_secret_file,omitempty"`
+
+	// Audiences Additional client IDs accepted in ID tokens, e.g. of the iOS and Android apps
+	Audiences []string `json:"audiences,omitempty" yaml:"audiences,omitempty"`
+
+	// RedirectURL Callback URL registered at the provider
+	RedirectURL string `json:"redirect_url" yaml:"redirect_url"`
+
+	// ProxyURL Optional proxy for the calls to the provider
+	ProxyURL string `json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+
+	// Scopes Scopes requested instead of the provider defaults
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+
+	// Endpoint Optional base URL replacing the one of the provider, e.g. an internal gateway
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// Endpoints Optional URLs of single endpoints by name, e.g. "token" or "keys"
+	Endpoints map[string]string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+
+	// TeamID Apple developer team ID
+	TeamID string `json:"team_id,omitempty" yaml:"team_id,omitempty"`
+
+	// KeyID Apple Sign in with Apple private key ID
+	KeyID string `json:"key_id,omitempty" yaml:"key_id,omitempty"`
+
+	// PrivateKey Apple PEM private key
+	PrivateKey string `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+
+	// PrivateKeyFile Apple .p8 private key file
+	PrivateKeyFile string `json:"private_key_file,omitempty" yaml:"private_key_file,omitempty"`
+
+	// GraphVersion Facebook Graph API version, e.g. "v18.0"
+	GraphVersion string `json:"graph_version,omitempty" yaml:"graph_version,omitempty"`
+
+	// BotPrompt LINE option to add the LINE Official Account of the channel as a friend: "normal" or "aggressive"
+	BotPrompt string `json:"bot_prompt,omitempty" yaml:"bot_prompt,omitempty"`
+
+	// Issuer OpenID Connect issuer, whose discovery document configures the provider
+	Issuer string `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+
+	// Tenant Microsoft tenant authority: common (default), organizations, consumers, or a tenant ID or domain
+	Tenant string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+
+	// AllowedTenants Microsoft tenant IDs accepted in ID tokens, every tenant of the authority when empty; required with a tenant domain
+	AllowedTenants []string `json:"allowed_tenants,omitempty" yaml:"allowed_tenants,omitempty"`
+
+	// OAuth2 Endpoints and user mapping of a generic OAuth 2.0 provider
+	OAuth2 *OAuth2Config `json:"oauth2,omitempty" yaml:"oauth2,omitempty"`
+}
+
+// authTypes are the provider types accepted in a configuration.
+var authTypes = []AuthType{AuthGoogle, AuthApple, AuthFacebook, AuthLine, AuthOIDC, AuthOAuth2, AuthGitHub, AuthMicrosoft, AuthKakao}
+
+var graphVersionPattern = regexp.MustCompile(`^v[0-9]+\.[0-9]+$`)
+
+// LoadConfig reads and validates a JSON or YAML configuration file, by its extension.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := ConfigJSON
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = ConfigYAML
+	}
+	return ParseConfig(data, format)
+}
+
+// ParseConfig decodes and validates a configuration. Unknown fields are rejected.
+func ParseConfig(data []byte, format ConfigFormat) (*Config, error) {
+	config := &Config{}
+	switch format {
+	case ConfigJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	case ConfigYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidConfig, format)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// LoadConfigEnv reads and validates a configuration from environment variables.
+//
+// The providers are listed in {prefix}_PROVIDERS, separated by commas, and each field of a provider
+// is read from {prefix}_{NAME}_{FIELD}, e.g. for the prefix OAUTH:
+//
+//	OAUTH_PROVIDERS=google,acme
+//	OAUTH_GOOGLE_CLIENT_ID=...
+//	OAUTH_GOOGLE_CLIENT_SECRET_FILE=/run/secrets/google
+//	OAUTH_ACME_TYPE=OIDC
+//	OAUTH_ACME_ISSUER=https://acme.okta.com
+//
+// The fields are the upper case names of the JSON fields, the OAuth2 endpoints included (AUTH_URL, TOKEN_URL,
+// USERINFO_URL, REVOKE_URL, AUTH_STYLE). Scopes are separated by commas or spaces. Single endpoints are
+// read from ENDPOINT_{NAME}, e.g. OAUTH_ACME_ENDPOINT_TOKEN.
+func LoadConfigEnv(prefix string) (*Config, error) {
+	config, err := configFromEnv(prefix, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// configFromEnv builds a configuration from the variables returned by lookup.
+func configFromEnv(prefix string, lookup func(string) (string, bool)) (*Config, error) {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+	if prefix != "" {
+		prefix += "_"
+	}
+	names, _ := lookup(prefix + "PROVIDERS")
+	config := &Config{}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		key := prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)) + "_"
+		env := func(field string) string {
+			value, _ := lookup(key + field)
+			return value
+		}
+		provider := ProviderConfig{
+			Name:             name,
+			Type:             AuthType(env("TYPE")),
+			ClientID:         env("CLIENT_ID"),
+			ClientSecret:     env("CLIENT_SECRET"),
+			ClientSecretFile: env("CLIENT_SECRET_FILE"),
+			Audiences:        strings.FieldsFunc(env("AUDIENCES"), isScopeSeparator),
+			RedirectURL:      env("REDIRECT_URL"),
+			ProxyURL:         env("PROXY_URL"),
+			Scopes:           strings.FieldsFunc(env("SCOPES"), isScopeSeparator),
+			TeamID:           env("TEAM_ID"),
+			KeyID:            env("KEY_ID"),
+			PrivateKey:       env("PRIVATE_KEY"),
+			PrivateKeyFile:   env("PRIVATE_KEY_FILE"),
+			GraphVersion:     env("GRAPH_VERSION"),
+			BotPrompt:        env("BOT_PROMPT"),
+			Issuer:           env("ISSUER"),
+			Tenant:           env("TENANT"),
+			AllowedTenants:   strings.FieldsFunc(env("ALLOWED_TENANTS"), isScopeSeparator),
+			Endpoint:         env("ENDPOINT"),
+		}
+		for _, endpoint := range endpointNames {
+			if value := env("ENDPOINT_" + strings.ToUpper(endpoint)); value != "" {
+				if provider.Endpoints == nil {
+					provider.Endpoints = map[string]string{}
+				}
+				provider.Endpoints[endpoint] = value
+			}
+		}
+		if provider.Type == "" {
+			provider.Type = AuthType(name)
+		}
+		if authURL, tokenURL := env("AUTH_URL"), env("TOKEN_URL"); authURL != "" || tokenURL != "" {
+			provider.OAuth2 = &OAuth2Config{
+				AuthURL:     authURL,
+				TokenURL:    tokenURL,
+				UserInfoURL: env("USERINFO_URL"),
+				RevokeURL:   env("REVOKE_URL"),
+			}
+			if style := env("AUTH_STYLE"); style != "" {
+				if err := provider.OAuth2.AuthStyle.UnmarshalText([]byte(style)); err != nil {
+					return nil, &ConfigError{Field: providerField(name, 0, "oauth2.auth_style"), Err: err}
+				}
+			}
+		}
+		config.Providers = append(config.Providers, provider)
+	}
+	return config, nil
+}
+
+// isScopeSeparator splits the lists of scopes and audiences given in environment variables.
+func isScopeSeparator(r rune) bool {
+	return r == ',' || r == ' '
+}
+
+// Validate normalizes the provider types and names, reads the secret files,
+// and returns a ConfigError for the first invalid field.
+func (c *Config) Validate() error {
+	if len(c.Providers) == 0 {
+		return &ConfigError{Field: "providers", Err: errors.New("no provider configured")}
+	}
+	names := map[string]bool{}
+	for i := range c.Providers {
+		provider := &c.Providers[i]
+		if err := provider.validate(i); err != nil {
+			return err
+		}
+		if names[provider.Name] {
+			return &ConfigError{Field: providerField(provider.Name, i, "name"), Err: errors.New("duplicate provider name")}
+		}
+		names[provider.Name] = true
+	}
+	return nil
+}
+
+// validate normalizes and validates the configuration of the i-th provider.
+func (c *ProviderConfig) validate(i int) error {
+	field := func(name string) string {
+		return providerField(c.Name, i, name)
+	}
+	known := false
+	for _, authType := range authTypes {
+		if strings.EqualFold(string(c.Type), string(authType)) {
+			c.Type, known = authType, true
+		}
+	}
+	if !known {
+		return &ConfigError{Field: field("type"), Err: fmt.Errorf("unknown provider type %q", c.Type)}
+	}
+	if c.Name == "" {
+		c.Name = string(c.Type)
+	}
+	if c.ClientID == "" {
+		return &ConfigError{Field: field("client_id"), Err: errors.New("required")}
+	}
+	if c.RedirectURL == "" {
+		return &ConfigError{Field: field("redirect_url"), Err: errors.New("required")}
+	}
+	if err := validateRedirectURL(c.RedirectURL); err != nil {
+		return &ConfigError{Field: field("redirect_url"), Err: err}
+	}
+	if c.ProxyURL != "" {
+		if _, err := parseProxyURL(c.ProxyURL); err != nil {
+			return &ConfigError{Field: field("proxy_url"), Err: err}
+		}
+	}
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || u.Host == "" {
+			return &ConfigError{Field: field("endpoint"), Err: ErrInvalidRequestURL}
+		}
+	}
+	for name, endpoint := range c.Endpoints {
+		if !contains(endpointNames, name) {
+			return &ConfigError{Field: field("endpoints." + name), Err: errors.New("unknown endpoint")}
+		}
+		if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+			return &ConfigError{Field: field("endpoints." + name), Err: ErrInvalidRequestURL}
+		}
+	}
+	clientSecret, err := readSecret(c.ClientSecret, c.ClientSecretFile)
+	if err != nil {
+		return &ConfigError{Field: field("client_secret_file"), Err: err}
+	}
+	privateKey, err := readSecret(c.PrivateKey, c.PrivateKeyFile)
+	if err != nil {
+		return &ConfigError{Field: field("private_key_file"), Err: err}
+	}
+
+	switch c.Type {
+	case AuthApple:
+		if clientSecret != "" {
+			break
+		}
+		if c.TeamID == "" {
+			return &ConfigError{Field: field("team_id"), Err: errors.New("required without client_secret")}
+		}
+		if c.KeyID == "" {
+			return &ConfigError{Field: field("key_id"), Err: errors.New("required without client_secret")}
+		}
+		if privateKey == "" {
+			return &ConfigError{Field: field("private_key_file"), Err: errors.New("required without client_secret")}
+		}
+		if _, err := ParseApplePrivateKey([]byte(privateKey)); err != nil {
+			return &ConfigError{Field: field("private_key"), Err: err}
+		}
+		return nil
+	case AuthFacebook:
+		if c.GraphVersion != "" && !graphVersionPattern.MatchString(c.GraphVersion) {
+			return &ConfigError{Field: field("graph_version"), Err: fmt.Errorf("expected a version such as %q", FacebookGraphVersion)}
+		}
+	case AuthLine:
+		if c.BotPrompt != "" && c.BotPrompt != "normal" && c.BotPrompt != "aggressive" {
+			return &ConfigError{Field: field("bot_prompt"), Err: errors.New(`expected "normal" or "aggressive"`)}
+		}
+	case AuthMicrosoft:
+		for _, tenant := range c.AllowedTenants {
+			if !tenantIDPattern.MatchString(tenant) {
+				return &ConfigError{Field: field("allowed_tenants"), Err: fmt.Errorf("expected a tenant ID, got %q", tenant)}
+			}
+		}
+		if isTenantDomain(c.Tenant) && len(c.AllowedTenants) == 0 {
+			return &ConfigError{Field: field("allowed_tenants"), Err: fmt.Errorf("required with the tenant domain %q", c.Tenant)}
+		}
+	case AuthOIDC:
+		if c.Issuer == "" {
+			return &ConfigError{Field: field("issuer"), Err: errors.New("required")}
+		}
+	case AuthOAuth2:
+		if c.OAuth2 == nil || c.OAuth2.AuthURL == "" {
+			return &ConfigError{Field: field("oauth2.auth_url"), Err: errors.New("required")}
+		}
+		if c.OAuth2.TokenURL == "" {
+			return &ConfigError{Field: field("oauth2.token_url"), Err: errors.New("required")}
+		}
+	}
+	if clientSecret == "" {
+		return &ConfigError{Field: field("client_secret"), Err: errors.New("required")}
+	}
+	return nil
+}
+
+// providerField names a field of a provider by the provider name, or by its index when it has no name yet.
+func providerField(name string, i int, field string) string {
+	if name == "" {
+		name = strconv.Itoa(i)
+	}
+	return "providers[" + name + "]." + field
+}
+
+// readSecret returns a secret given inline, or read from a file trimming the trailing newline.
+// The configuration is left unchanged so that it can be validated again.
+func readSecret(value, path string) (string, error) {
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", errors.New("both the value and the file are set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Registry builds every provider of a validated configuration.
+// The options are applied to the Service of each provider, e.g. WithHTTPClient.
+func (c *Config) Registry(ctx context.Context, options ...Option) (*Registry, error) {
+	registry := NewRegistry()
+	for i := range c.Providers {
+		provider, authOptions, err := c.Providers[i].Build(ctx, options...)
+		if err != nil {
+			return nil, err
+		}
+		if err = registry.RegisterNamed(c.Providers[i].Name, c.Providers[i].Type, provider, authOptions...); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Build creates the provider of a validated configuration,
+// with the AuthOptions to use for its authorization URLs.
+func (c *ProviderConfig) Build(ctx context.Context, options ...Option) (LoginProvider, []AuthOption, error) {
+	clientSecret, err := readSecret(c.ClientSecret, c.ClientSecretFile)
+	if err != nil {
+		return nil, nil, &ConfigError{Field: providerField(c.Name, 0, "client_secret_file"), Err: err}
+	}
+	defaults := []Option{WithRedirectURL(c.RedirectURL), WithProxyURL(c.ProxyURL), WithAudiences(c.Audiences...), WithEndpoint(c.Endpoint)}
+	if c.Type == AuthApple && clientSecret == "" {
+		privateKey, err := readSecret(c.PrivateKey, c.PrivateKeyFile)
+		if err != nil {
+			return nil, nil, &ConfigError{Field: providerField(c.Name, 0, "private_key_file"), Err: err}
+		}
+		key, err := ParseApplePrivateKey([]byte(privateKey))
+		if err != nil {
+			return nil, nil, &ConfigError{Field: providerField(c.Name, 0, "private_key"), Err: err}
+		}
+		secret := NewAppleSecret(c.TeamID, c.KeyID, c.ClientID, key)
+		if _, err = secret.Secret(); err != nil {
+			return nil, nil, err
+		}
+		defaults = append(defaults, WithAppleSecret(secret))
+	}
+	for name, endpoint := range c.Endpoints {
+		defaults = append(defaults, WithEndpointURL(name, endpoint))
+	}
+	options = append(defaults, options...)
+	service, err := NewService(c.ClientID, clientSecret, c.Type, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var authOptions []AuthOption
+	if len(c.Scopes) > 0 {
+		authOptions = append(authOptions, WithScopes(c.Scopes...))
+	}
+	switch c.Type {
+	case AuthGoogle:
+		return NewGoogle(service), authOptions, nil
+	case AuthApple:
+		return NewApple(service), authOptions, nil
+	case AuthFacebook:
+		facebook := NewFacebook(service)
+		if c.GraphVersion != "" {
+			facebook.GraphVersion = c.GraphVersion
+		}
+		return facebook, authOptions, nil
+	case AuthLine:
+		if c.BotPrompt != "" {
+			authOptions = append(authOptions, WithAuthParam("bot_prompt", c.BotPrompt))
+		}
+		return NewLine(service), authOptions, nil
+	case AuthGitHub:
+		return NewGitHub(service), authOptions, nil
+	case AuthMicrosoft:
+		microsoft := NewMicrosoft(service, c.Tenant)
+		microsoft.AllowedTenants = c.AllowedTenants
+		return microsoft, authOptions, nil
+	case AuthKakao:
+		return NewKakao(service), authOptions, nil
+	case AuthOIDC:
+		provider, err := NewOIDC(ctx, service, c.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		return provider, authOptions, nil
+	case AuthOAuth2:
+		if c.OAuth2 == nil {
+			return nil, nil, &ConfigError{Field: providerField(c.Name, 0, "oauth2"), Err: errors.New("required")}
+		}
+		provider, err := NewOAuth2(service, *c.OAuth2)
+		if err != nil {
+			return nil, nil, err
+		}
+		return provider, authOptions, nil
+	}
+	return nil, nil, &ConfigError{Field: providerField(c.Name, 0, "type"), Err: fmt.Errorf("unknown provider type %q", c.Type)}
+}
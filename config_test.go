@@ -0,0 +1,145 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	if err := os.WriteFile(filepath.Join(dir, "AuthKey.p8"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); nil != err {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "line-secret"), []byte("channel-secret\n"), 0o600); nil != err {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "providers.yaml")
+	err := os.WriteFile(path, []byte(`
+providers:
+  - type: apple
+    client_id: com.short.roll
+    redirect_url: https://example.com/callback/apple
+    team_id: TEAM123456
+    key_id: KEY1234567
+    private_key_file: `+filepath.Join(dir, "AuthKey.p8")+`
+  - type: line
+    client_id: "1234567890"
+    client_secret_file: `+filepath.Join(dir, "line-secret")+`
+    redirect_url: https://example.com/callback/line
+    bot_prompt: aggressive
+  - name: acme
+    type: OAuth2
+    client_id: acme
+    client_secret: secret
+    redirect_url: https://example.com/callback/acme
+    scopes: [read]
+    oauth2:
+      auth_url: https://acme.example.com/authorize
+      token_url: https://acme.example.com/token
+      auth_style: post
+`), 0o600)
+	if nil != err {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if config.Providers[1].Name != "Line" || config.Providers[2].OAuth2.AuthStyle != AuthStylePost {
+		t.Fatalf("unexpected config %+v", config.Providers)
+	}
+	if err = config.Validate(); nil != err {
+		t.Fatalf("expected a loaded configuration to validate again: %v", err)
+	}
+	if err = config.Validate(); nil != err {
+		t.Fatal(err)
+	}
+	registry, err := config.Registry(context.Background())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if providers := registry.List(); len(providers) != 3 || providers[2].Name != "acme" || len(providers[1].AuthOptions) != 1 {
+		t.Fatalf("unexpected providers %+v", providers)
+	}
+	line, _ := registry.Get("Line")
+	if secret := line.(*Line).service.ClientSecret; secret != "channel-secret" {
+		t.Fatalf("expected the secret read from the file, got %q", secret)
+	}
+
+	apple, _ := registry.Get("Apple")
+	secret, err := apple.(*Apple).service.secret()
+	if nil != err {
+		t.Fatal(err)
+	}
+	claims := &jwt.Claims{}
+	verifier := &jwt.Verifier{Algorithms: []string{jwt.ES256}, Keys: jwt.StaticKey(&key.PublicKey), Issuers: []string{"TEAM123456"}, Audiences: []string{AppleBaseEndpoint}}
+	if _, err = verifier.Verify(context.Background(), secret, claims); nil != err || claims.Subject != "com.short.roll" {
+		t.Fatalf("unexpected Apple client secret %v %+v", err, claims)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		config string
+		field  string
+	}{
+		{`{"providers":[{"type":"Google","client_secret":"secret","redirect_url":"https://example.com/callback"}]}`, "providers[Google].client_id"},
+		{`{"providers":[{"type":"Gogle","client_id":"client"}]}`, "providers[0].type"},
+		{`{"providers":[{"type":"Facebook","client_id":"app","client_secret":"secret","redirect_url":"https://example.com/callback","graph_version":"18"}]}`, "providers[Facebook].graph_version"},
+		{`{"providers":[{"type":"Apple","client_id":"app","redirect_url":"https://example.com/callback","team_id":"TEAM"}]}`, "providers[Apple].key_id"},
+		{`{"providers":[{"name":"acme","type":"OIDC","client_id":"app","client_secret":"secret","redirect_url":"/callback","issuer":"https://acme.example.com"}]}`, "providers[acme].redirect_url"},
+		{`{"providers":[{"type":"Microsoft","client_id":"app","client_secret":"secret","redirect_url":"https://example.com/callback","tenant":"contoso.com"}]}`, "providers[Microsoft].allowed_tenants"},
+	} {
+		_, err := ParseConfig([]byte(test.config), ConfigJSON)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != test.field || !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("expected an error on %s, got %v", test.field, err)
+		}
+	}
+	if _, err := ParseConfig([]byte(`{"providers":[{"type":"Google","clientid":"client"}]}`), ConfigJSON); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected unknown fields to be rejected, got %v", err)
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	env := map[string]string{
+		"OAUTH_PROVIDERS":                  "google, acme-okta",
+		"OAUTH_GOOGLE_CLIENT_ID":           "client",
+		"OAUTH_GOOGLE_CLIENT_SECRET":       "secret",
+		"OAUTH_GOOGLE_REDIRECT_URL":        "https://example.com/callback/google",
+		"OAUTH_GOOGLE_SCOPES":              "openid email",
+		"OAUTH_GOOGLE_ENDPOINT_TOKEN":      "https://gateway.example.com/google/token",
+		"OAUTH_ACME_OKTA_TYPE":             "oidc",
+		"OAUTH_ACME_OKTA_CLIENT_ID":        "acme",
+		"OAUTH_ACME_OKTA_CLIENT_SECRET":    "secret",
+		"OAUTH_ACME_OKTA_REDIRECT_URL":     "https://example.com/callback/acme-okta",
+		"OAUTH_ACME_OKTA_ISSUER":           "https://acme.okta.com",
+		"OAUTH_ACME_OKTA_CLIENT_ID_UNUSED": "ignored",
+	}
+	config, err := configFromEnv("oauth", func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err = config.Validate(); nil != err {
+		t.Fatal(err)
+	}
+	google, acme := config.Providers[0], config.Providers[1]
+	if google.Type != AuthGoogle || len(google.Scopes) != 2 || google.Endpoints[EndpointToken] != "https://gateway.example.com/google/token" || acme.Name != "acme-okta" || acme.Type != AuthOIDC || acme.Issuer != "https://acme.okta.com" {
+		t.Fatalf("unexpected config %+v", config.Providers)
+	}
+}
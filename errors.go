@@ -0,0 +1,232 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+var (
+	ErrRateLimited         = errors.New("rate limited by provider")
+	ErrProviderUnavailable = errors.New("provider unavailable")
+)
+
+// ProviderError is returned when a provider answers with an unsuccessful status code.
+// It carries the OAuth error fields parsed from the response body and matches the
+// package sentinels (ErrInvalidClientID, ErrInvalidAccessToken, ErrRateLimited, ...) with errors.Is.
+type ProviderError struct {
+	// Provider that returned the error.
+	Provider AuthType
+
+	// StatusCode HTTP status code of the response.
+	StatusCode int
+
+	// Code OAuth error code (e.g. "invalid_grant"), or the error type for Graph API errors.
+	Code string
+
+	// Description Human-readable error description.
+	Description string
+
+	// URI Optional page with more information about the error.
+	URI string
+
+	// ErrorCode Numeric error code, used by the Facebook Graph API, Google APIs and the Kakao API.
+	ErrorCode int
+
+	// ErrorSubcode Numeric error subcode, used by the Facebook Graph API.
+	ErrorSubcode int
+
+	// RetryAfter How long the provider asked to wait before retrying, zero if not specified.
+	RetryAfter time.Duration
+
+	// Body Raw response body, kept for diagnostics.
+	Body []byte
+
+	// Err Sentinel describing what the failed call was validating, e.g. ErrInvalidRefreshToken.
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(string(e.Provider)))
+	if e.Code != "" {
+		b.WriteString(": " + e.Code)
+	}
+	if e.Description != "" {
+		b.WriteString(": " + e.Description)
+	}
+	fmt.Fprintf(&b, " (status %d)", e.StatusCode)
+	return b.String()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Is maps the OAuth error code and status to the package sentinels.
+func (e *ProviderError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.Code == "slow_down" || e.isGraphRateLimit()
+	case ErrProviderUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError || e.Code == "server_error" || e.Code == "temporarily_unavailable"
+	case ErrInvalidClientID, ErrInvalidClientSecret:
+		return e.Code == "invalid_client" || e.Code == "unauthorized_client" || e.Code == "incorrect_client_credentials"
+	case ErrInvalidRedirectURL:
+		return e.Code == "redirect_uri_mismatch" || e.Code == "invalid_redirect_uri"
+	case ErrInvalidAccessToken:
+		return e.Code == "invalid_token" || e.Code == "UNAUTHENTICATED" || (e.Provider == AuthFacebook && e.ErrorCode == 190) ||
+			(e.Provider == AuthKakao && e.ErrorCode == -401)
+	}
+	return false
+}
+
+// isGraphRateLimit reports whether the error is one of the Facebook Graph API throttling codes.
+func (e *ProviderError) isGraphRateLimit() bool {
+	if e.Provider != AuthFacebook {
+		return false
+	}
+	switch e.ErrorCode {
+	case 4, 17, 32, 613:
+		return true
+	}
+	return false
+}
+
+// providerErrorBody covers the flat OAuth 2.0 error format (RFC 6749 section 5.2),
+// the nested Facebook Graph and Google API formats, LINE's message-only format and the Kakao API msg and code format.
+type providerErrorBody struct {
+	Error            json.RawMessage `json:"error"`
+	ErrorDescription string          `json:"error_description"`
+	ErrorURI         string          `json:"error_uri"`
+	Message          string          `json:"message"`
+	Msg              string          `json:"msg"`
+	Code             json.RawMessage `json:"code"`
+}
+
+// nestedErrorBody is the "error" object used by the Facebook Graph API and Google APIs.
+type nestedErrorBody struct {
+	Message      string      `json:"message"`
+	Type         string      `json:"type"`
+	Code         int         `json:"code"`
+	ErrorSubcode int         `json:"error_subcode"`
+	Status       string      `json:"status"`
+	FbtraceID    interface{} `json:"fbtrace_id"`
+}
+
+// newProviderError builds a ProviderError from an unsuccessful response and its body.
+// kind is the sentinel describing what the call was validating. It is not attached to transient failures
+// (outages and rate limits), so that a token is not taken for invalid because the provider is unavailable.
+func newProviderError(provider AuthType, resp *http.Response, body []byte, kind error) *ProviderError {
+	e := &ProviderError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Body:       body,
+	}
+	e.parse(body)
+	if !e.Is(ErrProviderUnavailable) && !e.Is(ErrRateLimited) {
+		e.Err = kind
+	}
+	return e
+}
+
+// parse reads the error fields of the response body.
+func (e *ProviderError) parse(body []byte) {
+	var value providerErrorBody
+	if err := json.Unmarshal(body, &value); err != nil {
+		e.Description = http.StatusText(e.StatusCode)
+		return
+	}
+	e.Description = value.ErrorDescription
+	e.URI = value.ErrorURI
+
+	var code string
+	var nested nestedErrorBody
+	switch {
+	case json.Unmarshal(value.Error, &code) == nil:
+		e.Code = code
+	case json.Unmarshal(value.Error, &nested) == nil:
+		e.Code = nested.Type
+		if e.Code == "" {
+			e.Code = nested.Status
+		}
+		e.Description = nested.Message
+		e.ErrorCode = nested.Code
+		e.ErrorSubcode = nested.ErrorSubcode
+	}
+	if e.Description == "" {
+		e.Description = value.Message
+	}
+	if e.Description == "" {
+		e.Description = value.Msg
+	}
+	if e.ErrorCode == 0 {
+		_ = json.Unmarshal(value.Code, &e.ErrorCode)
+	}
+}
+
+// TokenError is returned when a token fails verification.
+// It matches its Kind sentinel (ErrInvalidSignature or ErrInvalidIdToken) with errors.Is
+// and unwraps to the underlying jwt error, such as jwt.ErrExpired.
+type TokenError struct {
+	// Provider that issued the token.
+	Provider AuthType
+
+	// Kind Sentinel classifying the failure.
+	Kind error
+
+	// Err Underlying cause.
+	Err error
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", strings.ToLower(string(e.Provider)), e.Kind, e.Err)
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TokenError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// tokenError classifies a jwt verification error. Errors that do not come from
+// the jwt package, such as key fetch failures, are returned unchanged.
+func tokenError(provider AuthType, err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrInvalidSignature), errors.Is(err, jwt.ErrInvalidKey):
+		return &TokenError{Provider: provider, Kind: ErrInvalidSignature, Err: err}
+	case errors.Is(err, jwt.ErrMalformed), errors.Is(err, jwt.ErrUnsupportedAlgorithm),
+		errors.Is(err, jwt.ErrAlgorithmNotAllowed), errors.Is(err, jwt.ErrUnsupportedCritical),
+		errors.Is(err, jwt.ErrExpired), errors.Is(err, jwt.ErrMissingExpiry),
+		errors.Is(err, jwt.ErrNotValidYet), errors.Is(err, jwt.ErrIssuedInFuture),
+		errors.Is(err, jwt.ErrInvalidIssuer), errors.Is(err, jwt.ErrInvalidAudience):
+		return &TokenError{Provider: provider, Kind: ErrInvalidIdToken, Err: err}
+	}
+	return err
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
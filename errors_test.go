@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProviderError(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}}
+	err := newProviderError(AuthApple, resp, []byte(`{"error":"invalid_grant","error_description":"code expired"}`), ErrInvalidIdCode)
+	if err.Code != "invalid_grant" || err.Description != "code expired" {
+		t.Fatalf("unexpected error fields: %+v", err)
+	}
+	if !errors.Is(err, ErrInvalidIdCode) {
+		t.Fatal("expected ErrInvalidIdCode")
+	}
+
+	err = newProviderError(AuthLine, resp, []byte(`{"error":"invalid_client"}`), ErrInvalidRefreshToken)
+	if !errors.Is(err, ErrInvalidClientID) || errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("unexpected sentinel matching for %v", err)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}}
+	err = newProviderError(AuthFacebook, resp, []byte(`{"error":{"message":"Error validating access token","type":"OAuthException","code":190,"error_subcode":463,"fbtrace_id":"A1"}}`), nil)
+	if err.Code != "OAuthException" || err.ErrorCode != 190 || err.ErrorSubcode != 463 {
+		t.Fatalf("unexpected graph error fields: %+v", err)
+	}
+	if !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatal("expected ErrInvalidAccessToken")
+	}
+
+	resp = &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}}
+	err = newProviderError(AuthGoogle, resp, []byte(`{"error":{"code":401,"message":"Request had invalid authentication credentials.","status":"UNAUTHENTICATED"}}`), nil)
+	if err.Code != "UNAUTHENTICATED" || !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("unexpected google error: %+v", err)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"7"}}}
+	err = newProviderError(AuthLine, resp, []byte(`{"message":"Too many requests"}`), nil)
+	if err.RetryAfter != 7*time.Second || err.Description != "Too many requests" || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("unexpected rate limit error: %+v", err)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	err = newProviderError(AuthGoogle, resp, []byte(`{"error":"temporarily_unavailable"}`), ErrInvalidRefreshToken)
+	if !errors.Is(err, ErrProviderUnavailable) || errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected an outage not to match ErrInvalidRefreshToken: %v", err)
+	}
+	resp = &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}}
+	err = newProviderError(AuthFacebook, resp, []byte(`{"error":{"message":"Application request limit reached","type":"OAuthException","code":4}}`), ErrInvalidAccessToken)
+	if !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("expected a rate limit not to match ErrInvalidAccessToken: %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("Wed, 01 Nov 2023 00:00:30 GMT", now); d != 30*time.Second {
+		t.Fatalf("expected 30s, got %v", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Fatalf("expected 0, got %v", d)
+	}
+}
@@ -1,7 +1,41 @@
 package oauth
 
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
 type Facebook struct {
 	service *Service
+	keys    *jwks.Client
+
+	// GraphVersion Graph API version used by the provider, FacebookGraphVersion by default
+	GraphVersion string
+}
+
+// FacebookUser struct represents the fields of the Graph API user returned by Identify.
+type FacebookUser struct {
+	// ID App-scoped user ID
+	ID string `json:"id"`
+
+	// Name Full name of the user
+	Name string `json:"name"`
+
+	// Email Primary email address, only present with the email permission
+	Email string `json:"email"`
+
+	// Picture Profile picture of the user
+	Picture struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	} `json:"picture"`
 }
 
 // https://developers.facebook.com/docs/apps/for-business#field
@@ -15,15 +49,150 @@ const (
 	FacebookGraphEndpoint      = "https://graph.facebook.com"
 	FacebookGraphVideoEndpoint = "https://graph-video.facebook.com"
 	FacebookWWWEndpoint        = "https://www.facebook.com"
+
+	// FacebookURLLimitedLoginKeys is the JWKS of the Limited Login authentication tokens.
+	FacebookURLLimitedLoginKeys = "https://limited.facebook.com/.well-known/oauth/openid/jwks/"
 )
 
+// FacebookClaims struct represents the claims in a Facebook Limited Login authentication token.
+type FacebookClaims struct {
+	// Expiration time of the token
+	Exp int64 `json:"exp"`
+
+	// Issued at time of the token
+	Iat int64 `json:"iat"`
+
+	// Issuer of the token, FacebookWWWEndpoint
+	Iss string `json:"iss"`
+
+	// Audience of the token, the app ID
+	Aud string `json:"aud"`
+
+	// Subject of the token, the app-scoped user ID
+	Sub string `json:"sub"`
+
+	// Unique identifier of the token
+	Jti string `json:"jti"`
+
+	// Nonce passed to the Limited Login request
+	Nonce string `json:"nonce"`
+
+	// Email address of the user, with the email permission
+	Email string `json:"email"`
+
+	// Full name of the user
+	Name string `json:"name"`
+
+	// Profile picture URL
+	Picture string `json:"picture"`
+}
+
+// FacebookGraphVersion is the default Graph API version used by the Facebook provider.
+const FacebookGraphVersion = "v18.0"
+
+// NewFacebook creates a new instance of the Facebook Login provider.
+// URLs are derived from the Endpoint of the service, FacebookGraphEndpoint by default.
 func NewFacebook(service *Service) *Facebook {
-	return &Facebook{service: service}
+	if service.Endpoint == "" {
+		service.Endpoint = FacebookGraphEndpoint
+	}
+	keys := service.url(EndpointKeys, FacebookGraphEndpoint, FacebookURLLimitedLoginKeys)
+	return &Facebook{service: service, keys: service.keys(AuthFacebook, keys), GraphVersion: FacebookGraphVersion}
+}
+
+// AuthCodeURL builds the Facebook Login dialog URL, requesting the email and public_profile permissions by default.
+//
+// documentation https://developers.facebook.com/docs/facebook-login/guides/advanced/manual-flow#login
+func (p *Facebook) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.url(EndpointAuthorize, FacebookWWWEndpoint+"/"+p.version()+"/dialog/oauth"), authDefaults{
+		scopes:    []string{"email", "public_profile"},
+		separator: ",",
+	}, options...)
+}
+
+// IDToken verifies a Limited Login authentication token: its signature against Facebook's published keys,
+// issuer, audience and expiry. The nonce of the login request is checked by the caller.
+//
+// documentation https://developers.facebook.com/docs/facebook-login/limited-login/token/validating
+func (p *Facebook) IDToken(token string) (*FacebookClaims, error) {
+	if token == "" {
+		return nil, ErrInvalidIdToken
+	}
+	verifier := &jwt.Verifier{
+		Algorithms: []string{jwt.RS256},
+		Keys:       keyFunc(AuthFacebook, p.keys),
+		Issuers:    []string{FacebookWWWEndpoint},
+		Audiences:  p.service.audiences(),
+	}
+	claims := &FacebookClaims{}
+	if _, err := verifier.Verify(context.Background(), token, claims); err != nil {
+		return nil, tokenError(AuthFacebook, err)
+	}
+	return claims, nil
+}
+
+// Exchange exchanges an authorization code for an access token.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+//
+// documentation https://developers.facebook.com/docs/facebook-login/guides/advanced/manual-flow#confirm
+func (p *Facebook) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	return p.service.token(ctx, AuthFacebook, p.url(EndpointToken, FacebookGraphEndpoint+"/"+p.version()+"/oauth/access_token"), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
+}
+
+// Identify returns the user of a code exchange from the Graph API.
+//
+// documentation https://developers.facebook.com/docs/graph-api/reference/user
+func (p *Facebook) Identify(ctx context.Context, token *Token, _ string) (*Identity, error) {
+	if token.AccessToken == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	u := p.url(EndpointProfile, FacebookGraphEndpoint+"/"+p.version()+"/me") + "?" + url.Values{"fields": []string{"id,name,email,picture"}}.Encode()
+	var raw json.RawMessage
+	err := p.service.request(u, http.MethodGet,
+		WithTimeout(30*time.Second),
+		WithHeader(http.Header{"Authorization": []string{"Bearer " + token.AccessToken}}),
+		WithErrorDecoder(providerErrors(AuthFacebook, ErrInvalidAccessToken)),
+	).DoJSON(ctx, &raw)
+	if err != nil {
+		return nil, err
+	}
+	user := &FacebookUser{}
+	if err = json.Unmarshal(raw, user); err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Provider: AuthFacebook,
+		Subject:  user.ID,
+		Email:    user.Email,
+		Name:     user.Name,
+		Picture:  user.Picture.Data.URL,
+		Raw:      rawClaims(raw),
+	}, nil
+}
+
+// IdentityCode verifies the Facebook authorization code, with the PKCE verifier when one was used.
+func (p *Facebook) IdentityCode(code string, codeVerifier ...string) error {
+	var verifier string
+	if len(codeVerifier) > 0 {
+		verifier = codeVerifier[0]
+	}
+	_, err := p.Exchange(context.Background(), code, verifier)
+	return err
 }
-func (p Facebook) IDToken(token string) error {
-	return nil
+
+// version returns the Graph API version of the provider.
+func (p *Facebook) version() string {
+	if p.GraphVersion == "" {
+		return FacebookGraphVersion
+	}
+	return p.GraphVersion
 }
 
-func (p Facebook) IdentityCode(token string) error {
-	return nil
+// url returns the URL of the named endpoint, fallback being its default.
+func (p *Facebook) url(name, fallback string) string {
+	return p.service.url(name, FacebookGraphEndpoint, fallback)
 }
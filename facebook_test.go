@@ -1,8 +1,11 @@
 package oauth
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+
+	"socialgoauth.org/social-goauth/oauthtest"
 )
 
 func TestFacebook(t *testing.T) {
@@ -12,3 +15,27 @@ func TestFacebook(t *testing.T) {
 	}
 	fmt.Println(service)
 }
+
+func TestFacebookIDToken(t *testing.T) {
+	server := oauthtest.NewFacebook("app", "secret")
+	defer server.Close()
+	service, err := NewService("app", "secret", AuthFacebook, WithTransport(server.Transport()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	facebook := NewFacebook(service)
+
+	claims, err := facebook.IDToken(server.IDToken("nonce", nil))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if claims.Sub != "000001.test" || claims.Iss != FacebookWWWEndpoint || claims.Nonce != "nonce" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if _, err = facebook.IDToken(server.IDToken("", map[string]interface{}{"aud": "other"})); !errors.Is(err, ErrInvalidIdToken) {
+		t.Fatalf("expected ErrInvalidIdToken for another audience, got %v", err)
+	}
+	if _, err = facebook.IDToken(""); !errors.Is(err, ErrInvalidIdToken) {
+		t.Fatalf("expected ErrInvalidIdToken for an empty token, got %v", err)
+	}
+}
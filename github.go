@@ -0,0 +1,212 @@
+package oauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	GitHubBaseEndpoint   = "https://github.com"
+	GitHubAPIEndpoint    = "https://api.github.com"
+	GitHubURLAuthorize   = GitHubBaseEndpoint + "/login/oauth/authorize"
+	GitHubURLAccessToken = GitHubBaseEndpoint + "/login/oauth/access_token"
+
+	// GitHubAPIVersion is the REST API version requested by the GitHub provider.
+	GitHubAPIVersion = "2022-11-28"
+)
+
+// GitHubUser struct represents the fields of the GitHub user returned by /user.
+type GitHubUser struct {
+	// ID Unique and stable numeric ID of the user
+	ID int64 `json:"id"`
+
+	// Login Username, which the user can change
+	Login string `json:"login"`
+
+	// Name Display name of the user
+	Name string `json:"name"`
+
+	// Email Public email address of the user, empty unless they chose to show one
+	Email string `json:"email"`
+
+	// AvatarURL Profile picture URL
+	AvatarURL string `json:"avatar_url"`
+
+	// HTMLURL Profile page URL
+	HTMLURL string `json:"html_url"`
+
+	// Raw Fields as returned by GitHub
+	Raw map[string]interface{} `json:"-"`
+}
+
+// GitHubEmail struct represents an email address returned by /user/emails.
+type GitHubEmail struct {
+	// Email address
+	Email string `json:"email"`
+
+	// Primary Indicates the primary address of the account
+	Primary bool `json:"primary"`
+
+	// Verified Indicates if GitHub verified the address
+	Verified bool `json:"verified"`
+
+	// Visibility "public", "private" or empty
+	Visibility string `json:"visibility"`
+}
+
+// GitHub struct represents the GitHub OAuth App provider.
+type GitHub struct {
+	service *Service
+}
+
+// NewGitHub creates a new instance of the GitHub OAuth provider.
+// URLs are derived from the Endpoint of the service, GitHubBaseEndpoint by default. Any other Endpoint is
+// the base URL of a GitHub Enterprise Server, whose REST API is served under /api/v3.
+func NewGitHub(service *Service) *GitHub {
+	if service.Endpoint == "" {
+		service.Endpoint = GitHubBaseEndpoint
+	}
+	return &GitHub{service: service}
+}
+
+// AuthCodeURL builds the GitHub authorization URL, requesting the read:user and user:email scopes by default.
+//
+// documentation https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps#1-request-a-users-github-identity
+func (p *GitHub) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.service.url(EndpointAuthorize, GitHubBaseEndpoint, GitHubURLAuthorize), authDefaults{
+		scopes: []string{"read:user", "user:email"},
+	}, options...)
+}
+
+// Exchange exchanges an authorization code for an access token.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+// GitHub OAuth Apps issue no refresh or ID tokens.
+//
+// documentation https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps#2-users-are-redirected-back-to-your-site-by-github
+func (p *GitHub) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	return p.service.token(ctx, AuthGitHub, p.service.url(EndpointToken, GitHubBaseEndpoint, GitHubURLAccessToken), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
+}
+
+// User gets the profile of the user.
+//
+// documentation https://docs.github.com/en/rest/users/users#get-the-authenticated-user
+func (p *GitHub) User(ctx context.Context, accessToken string) (*GitHubUser, error) {
+	if accessToken == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	var raw json.RawMessage
+	if err := p.get(ctx, p.apiURL(EndpointUserInfo, "/user"), accessToken, &raw); err != nil {
+		return nil, err
+	}
+	user := &GitHubUser{}
+	if err := json.Unmarshal(raw, user); err != nil {
+		return nil, err
+	}
+	user.Raw = rawClaims(raw)
+	return user, nil
+}
+
+// Emails gets the email addresses of the user. Requires the user:email scope.
+//
+// documentation https://docs.github.com/en/rest/users/emails#list-email-addresses-for-the-authenticated-user
+func (p *GitHub) Emails(ctx context.Context, accessToken string) ([]GitHubEmail, error) {
+	if accessToken == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	var emails []GitHubEmail
+	if err := p.get(ctx, p.apiURL(EndpointEmails, "/user/emails"), accessToken, &emails); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// Identify returns the user of a code exchange with their primary verified email address.
+// When the token was granted without the user:email scope, or the user scope which includes it, the public email address of the profile is used, unverified.
+// GitHub has no ID token, the nonce is not used.
+func (p *GitHub) Identify(ctx context.Context, token *Token, _ string) (*Identity, error) {
+	user, err := p.User(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Provider: AuthGitHub,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Email:    user.Email,
+		Name:     user.Name,
+		Picture:  user.AvatarURL,
+		Raw:      user.Raw,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	if scopes := strings.FieldsFunc(token.Scope, isScopeSeparator); token.Scope != "" && !contains(scopes, "user:email") && !contains(scopes, "user") {
+		return identity, nil
+	}
+	emails, err := p.Emails(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			identity.Email, identity.EmailVerified = email.Email, true
+			break
+		}
+	}
+	return identity, nil
+}
+
+// Revoke revokes an access token with the OAuth Apps API, authenticated with the client credentials.
+//
+// documentation https://docs.github.com/en/rest/apps/oauth-applications#delete-an-app-token
+func (p *GitHub) Revoke(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidAccessToken
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(p.service.ClientID + ":" + p.service.ClientSecret))
+	return p.service.request(p.apiURL(EndpointRevoke, "/applications/"+url.PathEscape(p.service.ClientID)+"/token"), http.MethodDelete,
+		WithTimeout(30*time.Second),
+		WithHeader(p.header("Basic "+credentials)),
+		WithBody(map[string]string{"access_token": token}),
+		WithErrorDecoder(providerErrors(AuthGitHub, ErrInvalidAccessToken)),
+	).DoJSON(ctx, nil)
+}
+
+// get performs a GET request to the REST API authorized with the user's access token.
+func (p *GitHub) get(ctx context.Context, u, accessToken string, out interface{}) error {
+	return p.service.request(u, http.MethodGet,
+		WithTimeout(30*time.Second),
+		WithHeader(p.header("Bearer "+accessToken)),
+		WithErrorDecoder(providerErrors(AuthGitHub, ErrInvalidAccessToken)),
+	).DoJSON(ctx, out)
+}
+
+// header returns the headers of a REST API request.
+func (p *GitHub) header(authorization string) http.Header {
+	return http.Header{
+		"Authorization":        []string{authorization},
+		"Accept":               []string{"application/vnd.github+json"},
+		"X-Github-Api-Version": []string{GitHubAPIVersion},
+	}
+}
+
+// apiURL returns the URL of the named REST API endpoint at path: on api.github.com for github.com,
+// under /api/v3 for GitHub Enterprise Server.
+func (p *GitHub) apiURL(name, path string) string {
+	if u := p.service.Endpoints[name]; u != "" {
+		return u
+	}
+	if p.service.Endpoint == "" || p.service.Endpoint == GitHubBaseEndpoint {
+		return GitHubAPIEndpoint + path
+	}
+	return Endpoint(strings.TrimSuffix(p.service.Endpoint, "/"), "/api/v3"+path)
+}
@@ -0,0 +1,75 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"socialgoauth.org/social-goauth/oauthtest"
+)
+
+func TestGitHub(t *testing.T) {
+	server := oauthtest.NewGitHub("Iv1.8a61f9b3a7aba766", "secret")
+	defer server.Close()
+	redirectURL := "https://example.com/callback/github"
+	service, err := NewService(server.ClientID, server.ClientSecret, AuthGitHub, WithRedirectURL(redirectURL), WithEndpoint(server.URL))
+	if nil != err {
+		t.Fatal(err)
+	}
+	github := NewGitHub(service)
+	ctx := context.Background()
+
+	request, err := github.AuthCodeURL()
+	if nil != err {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(request.URL)
+	if u.Path != "/login/oauth/authorize" || u.Query().Get("scope") != "read:user user:email" {
+		t.Fatalf("unexpected authorization url %s", request.URL)
+	}
+	if u := github.apiURL(EndpointUserInfo, "/user"); u != server.URL+"/api/v3/user" {
+		t.Fatalf("unexpected enterprise api url %s", u)
+	}
+
+	token, err := github.Exchange(ctx, server.Code(redirectURL, "", ""), "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if token.RefreshToken != "" || token.IdToken != "" {
+		t.Fatalf("unexpected token %+v", token)
+	}
+	identity, err := github.Identify(ctx, token, "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if identity.Subject != "583231" || identity.Email != "rabbit@example.com" || !identity.EmailVerified || identity.Name != "Rabbit" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	token.Scope = "read:user"
+	if identity, err = github.Identify(ctx, token, ""); nil != err || identity.Email != "" || identity.EmailVerified {
+		t.Fatalf("unexpected identity without user:email %+v: %v", identity, err)
+	}
+	token.Scope = "user"
+	if identity, err = github.Identify(ctx, token, ""); nil != err || !identity.EmailVerified {
+		t.Fatalf("expected the user scope to grant the email addresses %+v: %v", identity, err)
+	}
+
+	_, err = github.Exchange(ctx, "bad", "")
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Code != "bad_verification_code" || !errors.Is(err, ErrInvalidIdCode) {
+		t.Fatalf("expected bad_verification_code, got %v", err)
+	}
+
+	if err = github.Revoke(ctx, token.AccessToken); nil != err {
+		t.Fatal(err)
+	}
+	if _, err = github.User(ctx, token.AccessToken); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("expected ErrInvalidAccessToken, got %v", err)
+	}
+
+	service, _ = NewService("client", "secret", AuthGitHub)
+	if u := NewGitHub(service).apiURL(EndpointEmails, "/user/emails"); u != GitHubAPIEndpoint+"/user/emails" {
+		t.Fatalf("unexpected api url %s", u)
+	}
+}
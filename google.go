@@ -1,17 +1,181 @@
 package oauth
 
+import (
+	"context"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+// Constants for Google URLs
+const (
+	GoogleAccountsEndpoint = "https://accounts.google.com"
+	GoogleURLCerts         = "https://www.googleapis.com/oauth2/v3/certs"
+	GoogleURLAuthorize     = GoogleAccountsEndpoint + "/o/oauth2/v2/auth"
+	GoogleURLToken         = "https://oauth2.googleapis.com/token"
+)
+
+// GoogleIssuers are the accepted issuers of Google ID tokens.
+var GoogleIssuers = []string{GoogleAccountsEndpoint, "accounts.google.com"}
+
+// GoogleClaims struct represents the claims in a Google ID Token.
+type GoogleClaims struct {
+	// Expiration time of the token
+	Exp int64 `json:"exp"`
+
+	// Issued at time of the token
+	Iat int64 `json:"iat"`
+
+	// Issuer of the token
+	Iss string `json:"iss"`
+
+	// Audience of the token, the client ID of the application
+	Aud string `json:"aud"`
+
+	// Authorized party, the client ID of the presenter of the token
+	Azp string `json:"azp"`
+
+	// Subject of the token, the unique Google account ID
+	Sub string `json:"sub"`
+
+	// Email address of the user
+	Email string `json:"email"`
+
+	// Indicates if the email is verified
+	EmailVerified bool `json:"email_verified"`
+
+	// Hosted G Suite domain of the user
+	Hd string `json:"hd"`
+
+	// Full name of the user
+	Name string `json:"name"`
+
+	// Profile picture URL
+	Picture string `json:"picture"`
+
+	// Given name of the user
+	GivenName string `json:"given_name"`
+
+	// Family name of the user
+	FamilyName string `json:"family_name"`
+
+	// Locale of the user
+	Locale string `json:"locale"`
+
+	// Nonce sent in the authorization request
+	Nonce string `json:"nonce"`
+}
+
+// Google struct represents the Google OAuth provider.
 type Google struct {
 	service *Service
+	keys    *jwks.Client
+}
+
+// NewGoogle creates a new instance of the Google OAuth provider.
+// URLs are derived from the Endpoint of the service, GoogleAccountsEndpoint by default.
+func NewGoogle(service *Service) *Google {
+	if service.Endpoint == "" {
+		service.Endpoint = GoogleAccountsEndpoint
+	}
+	return &Google{service: service, keys: service.keys(AuthGoogle, service.url(EndpointKeys, GoogleAccountsEndpoint, GoogleURLCerts))}
+}
+
+// url returns the URL of the named endpoint, fallback being its default.
+func (p *Google) url(name, fallback string) string {
+	return p.service.url(name, GoogleAccountsEndpoint, fallback)
 }
 
+// NewGoogle creates a new instance of the Google OAuth provider.
+//
+// Deprecated: use the package level NewGoogle function.
 func (p Google) NewGoogle(service *Service) *Google {
-	return &Google{service: service}
+	return NewGoogle(service)
+}
+
+// AuthCodeURL builds the Google authorization URL, requesting the openid, email and profile scopes by default.
+// Use WithAuthParam("access_type", "offline") to obtain a refresh token.
+//
+// documentation https://developers.google.com/identity/openid-connect/openid-connect#authenticationuriparameters
+func (p *Google) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.url(EndpointAuthorize, GoogleURLAuthorize), authDefaults{
+		scopes: []string{"openid", "email", "profile"},
+		nonce:  true,
+	}, options...)
+}
+
+// IDToken verifies a Google ID Token: its signature against Google's published keys, issuer, audience and expiry.
+//
+// documentation https://developers.google.com/identity/openid-connect/openid-connect#validatinganidtoken
+func (p *Google) IDToken(token string) (*GoogleClaims, error) {
+	if token == "" {
+		return nil, ErrInvalidIdToken
+	}
+	verifier := &jwt.Verifier{
+		Algorithms: []string{jwt.RS256},
+		Keys:       keyFunc(AuthGoogle, p.keys),
+		Issuers:    GoogleIssuers,
+		Audiences:  p.service.audiences(),
+	}
+	claims := &GoogleClaims{}
+	if _, err := verifier.Verify(context.Background(), token, claims); err != nil {
+		return nil, tokenError(AuthGoogle, err)
+	}
+	// Tokens obtained by the Android and iOS apps for the web client name the app in azp.
+	if err := p.service.authorizedParty(AuthGoogle, claims.Azp); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Identify verifies the ID token of a code exchange and returns the user it identifies.
+func (p *Google) Identify(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	claims, err := p.IDToken(token.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkNonce(AuthGoogle, nonce, claims.Nonce); err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Provider:      AuthGoogle,
+		Subject:       claims.Sub,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}
+	if parsed, err := jwt.Parse(token.IdToken); err == nil {
+		identity.Raw = rawClaims(parsed.Payload)
+	}
+	return identity, nil
+}
+
+// Exchange exchanges an authorization code for tokens.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+//
+// documentation https://developers.google.com/identity/protocols/oauth2/web-server#exchange-authorization-code
+func (p *Google) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	return p.service.token(ctx, AuthGoogle, p.url(EndpointToken, GoogleURLToken), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
 }
 
-func (p Google) IDToken(token string) error {
-	return nil
+// Refresh gets a new access token using a refresh token.
+//
+// documentation https://developers.google.com/identity/protocols/oauth2/web-server#offline
+func (p *Google) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, AuthGoogle, p.url(EndpointToken, GoogleURLToken), AuthStylePost, refreshToken)
 }
 
-func (p Google) IdentityCode(token string) error {
-	return nil
+// IdentityCode verifies the Google authorization code, with the PKCE verifier when one was used.
+func (p *Google) IdentityCode(code string, codeVerifier ...string) error {
+	var verifier string
+	if len(codeVerifier) > 0 {
+		verifier = codeVerifier[0]
+	}
+	_, err := p.Exchange(context.Background(), code, verifier)
+	return err
 }
@@ -1,8 +1,20 @@
 package oauth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
 )
 
 func TestGoogle(t *testing.T) {
@@ -15,3 +27,85 @@ func TestGoogle(t *testing.T) {
 	}
 	fmt.Println(service)
 }
+
+// rewriteTransport sends every request to a test server regardless of its host.
+type rewriteTransport struct {
+	target string
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(t.target)
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestGoogleIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if nil != err {
+		t.Fatal(err)
+	}
+	jwk, _ := jwks.NewJSONWebKey("g1", jwt.RS256, &key.PublicKey)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwks.JSONWebKey{jwk}})
+	}))
+	defer server.Close()
+
+	service, err := NewService("web.apps.googleusercontent.com", "secret", AuthGoogle, WithTransport(rewriteTransport{server.URL}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	google := NewGoogle(service)
+
+	claims := map[string]interface{}{
+		"iss": "https://accounts.google.com", "aud": "web.apps.googleusercontent.com", "sub": "1234",
+		"email": "rabbit@gmail.com", "email_verified": true,
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+	}
+	token, _ := jwt.Sign(jwt.RS256, "g1", claims, key)
+	value, err := google.IDToken(token)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if value.Sub != "1234" || !value.EmailVerified {
+		t.Fatalf("unexpected claims %+v", value)
+	}
+
+	claims["nonce"] = "nonce"
+	token, _ = jwt.Sign(jwt.RS256, "g1", claims, key)
+	identity, err := google.Identify(context.Background(), &Token{IdToken: token}, "nonce")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if identity.Subject != "1234" || identity.Email != "rabbit@gmail.com" || identity.Raw["nonce"] != "nonce" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	if _, err = google.Identify(context.Background(), &Token{IdToken: token}, "other"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("expected ErrInvalidNonce, got %v", err)
+	}
+
+	claims["aud"] = "other.apps.googleusercontent.com"
+	token, _ = jwt.Sign(jwt.RS256, "g1", claims, key)
+	if _, err = google.IDToken(token); !errors.Is(err, ErrInvalidIdToken) || !errors.Is(err, jwt.ErrInvalidAudience) {
+		t.Fatalf("expected an invalid audience error, got %v", err)
+	}
+
+	// Tokens of the mobile apps are accepted once their client IDs are configured.
+	service.Audiences = []string{"ios.apps.googleusercontent.com", "android.apps.googleusercontent.com"}
+	claims["aud"] = "ios.apps.googleusercontent.com"
+	token, _ = jwt.Sign(jwt.RS256, "g1", claims, key)
+	if _, err = google.IDToken(token); nil != err {
+		t.Fatal(err)
+	}
+	claims["aud"], claims["azp"] = "web.apps.googleusercontent.com", "android.apps.googleusercontent.com"
+	token, _ = jwt.Sign(jwt.RS256, "g1", claims, key)
+	if _, err = google.IDToken(token); nil != err {
+		t.Fatal(err)
+	}
+	claims["azp"] = "other.apps.googleusercontent.com"
+	token, _ = jwt.Sign(jwt.RS256, "g1", claims, key)
+	if _, err = google.IDToken(token); !errors.Is(err, ErrInvalidIdToken) {
+		t.Fatalf("expected an invalid azp error, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LoginProvider is a provider that supports the authorization code flow used by Handler.
+// Apple, Google, Facebook, Line, GitHub, Microsoft, Kakao, OIDC and OAuth2 implement it.
+type LoginProvider interface {
+	// AuthCodeURL builds the authorization URL the user is redirected to.
+	AuthCodeURL(options ...AuthOption) (*AuthRequest, error)
+
+	// Exchange exchanges the authorization code of the callback for tokens.
+	Exchange(ctx context.Context, code, codeVerifier string) (*Token, error)
+
+	// Identify returns the user authenticated by the tokens, checking the nonce of the ID token when not empty.
+	Identify(ctx context.Context, token *Token, nonce string) (*Identity, error)
+}
+
+var (
+	_ LoginProvider = (*Apple)(nil)
+	_ LoginProvider = (*Google)(nil)
+	_ LoginProvider = (*Facebook)(nil)
+	_ LoginProvider = (*Line)(nil)
+	_ LoginProvider = (*GitHub)(nil)
+	_ LoginProvider = (*Microsoft)(nil)
+	_ LoginProvider = (*Kakao)(nil)
+	_ LoginProvider = (*OIDC)(nil)
+	_ LoginProvider = (*OAuth2)(nil)
+)
+
+// ErrInvalidProvider is returned when a callback does not belong to the provider of its login state.
+var ErrInvalidProvider = errors.New("invalid provider")
+
+// SuccessFunc is called by the callback handler once the user is authenticated.
+type SuccessFunc func(w http.ResponseWriter, r *http.Request, identity *Identity, token *Token)
+
+// ErrorFunc is called by the login and callback handlers when the login fails.
+type ErrorFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// Handler implements the redirect flow of a provider with net/http handlers:
+// Login redirects to the provider and Callback authenticates the user when they come back.
+type Handler struct {
+	name        string
+	provider    LoginProvider
+	store       StateStore
+	authOptions []AuthOption
+	pkce        bool
+	onSuccess   SuccessFunc
+	onError     ErrorFunc
+}
+
+type HandlerOption func(*Handler)
+
+// WithAuthOptions sets the AuthOptions used for every authorization URL of the Handler.
+func WithAuthOptions(options ...AuthOption) HandlerOption {
+	return func(h *Handler) {
+		h.authOptions = append(h.authOptions, options...)
+	}
+}
+
+// WithoutPKCE disables PKCE for providers that reject the code_verifier parameter.
+func WithoutPKCE() HandlerOption {
+	return func(h *Handler) {
+		h.pkce = false
+	}
+}
+
+// WithOnSuccess sets the function called once the user is authenticated.
+// By default the user is redirected to the redirect_to parameter of the login, or to "/".
+func WithOnSuccess(fn SuccessFunc) HandlerOption {
+	return func(h *Handler) {
+		h.onSuccess = fn
+	}
+}
+
+// WithOnError sets the function called when the login fails.
+// By default an error status is written without details.
+func WithOnError(fn ErrorFunc) HandlerOption {
+	return func(h *Handler) {
+		h.onError = fn
+	}
+}
+
+// NewHandler creates the login and callback handlers of a provider.
+// name identifies the provider in the login state and in the paths registered by Register.
+func NewHandler(name string, provider LoginProvider, store StateStore, options ...HandlerOption) *Handler {
+	h := &Handler{
+		name:      name,
+		provider:  provider,
+		store:     store,
+		pkce:      true,
+		onSuccess: redirectOnSuccess,
+		onError:   statusOnError,
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// Register registers the handlers on /login/{name} and /callback/{name}.
+// The RedirectURL of the provider must point to the callback.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/login/"+h.name, h.Login)
+	mux.HandleFunc("/callback/"+h.name, h.Callback)
+}
+
+// Login saves a new login state and redirects the user to the provider.
+// A local redirect_to query parameter is kept in the login state for after the callback.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	options := h.authOptions
+	if h.pkce {
+		options = append(options[:len(options):len(options)], WithPKCE())
+	}
+	request, err := h.provider.AuthCodeURL(options...)
+	if err != nil {
+		h.onError(w, r, err)
+		return
+	}
+	state := NewLoginState(h.name, request)
+	if redirectTo := r.URL.Query().Get("redirect_to"); isLocalURL(redirectTo) {
+		state.RedirectTo = redirectTo
+	}
+	if err = h.store.Save(w, r, state); err != nil {
+		h.onError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, request.URL, http.StatusFound)
+}
+
+// Callback validates the state of the callback, exchanges the code and identifies the user.
+// It accepts both query and form_post responses. The login state is available to the
+// OnSuccess function with LoginStateFromContext.
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	if code := r.FormValue("error"); code != "" {
+		h.onError(w, r, &ProviderError{
+			Provider:    AuthType(h.name),
+			Code:        code,
+			Description: r.FormValue("error_description"),
+			URI:         r.FormValue("error_uri"),
+			Err:         ErrInvalidIdCode,
+		})
+		return
+	}
+	state, err := h.store.Consume(w, r, r.FormValue("state"))
+	if err != nil {
+		h.onError(w, r, err)
+		return
+	}
+	if state.Provider != h.name {
+		h.onError(w, r, ErrInvalidProvider)
+		return
+	}
+
+	ctx := r.Context()
+	token, err := h.provider.Exchange(ctx, r.FormValue("code"), state.CodeVerifier)
+	if err != nil {
+		h.onError(w, r, err)
+		return
+	}
+	identity, err := h.provider.Identify(ctx, token, state.Nonce)
+	if err != nil {
+		h.onError(w, r, err)
+		return
+	}
+	h.onSuccess(w, r.WithContext(context.WithValue(ctx, loginStateKey{}, state)), identity, token)
+}
+
+type loginStateKey struct{}
+
+// LoginStateFromContext returns the login state of a successful callback.
+func LoginStateFromContext(ctx context.Context) (*LoginState, bool) {
+	state, ok := ctx.Value(loginStateKey{}).(*LoginState)
+	return state, ok
+}
+
+// redirectOnSuccess redirects the user to the page the login was started from.
+func redirectOnSuccess(w http.ResponseWriter, r *http.Request, _ *Identity, _ *Token) {
+	redirectTo := "/"
+	if state, ok := LoginStateFromContext(r.Context()); ok && state.RedirectTo != "" {
+		redirectTo = state.RedirectTo
+	}
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+// statusOnError writes the status matching the error, without exposing its details.
+func statusOnError(w http.ResponseWriter, _ *http.Request, err error) {
+	status := http.StatusUnauthorized
+	switch {
+	case errors.Is(err, ErrStateNotFound), errors.Is(err, ErrInvalidProvider):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrProviderUnavailable), errors.Is(err, ErrRateLimited):
+		status = http.StatusBadGateway
+	case errors.Is(err, ErrInvalidRedirectURL), errors.Is(err, ErrInvalidState):
+		status = http.StatusInternalServerError
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// isLocalURL reports whether u is a path on the same site, so that it is safe to redirect to.
+// Browsers strip tabs and newlines and read backslashes as slashes, so "/\t/evil.com" or "/\\evil.com"
+// would lead to another site: control characters and backslashes are rejected anywhere.
+func isLocalURL(u string) bool {
+	for i := 0; i < len(u); i++ {
+		if u[i] < 0x20 || u[i] == 0x7f || u[i] == '\\' {
+			return false
+		}
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return false
+	}
+	return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//")
+}
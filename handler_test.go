@@ -0,0 +1,119 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var challenge string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "good" || CodeChallenge(r.PostFormValue("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"42","name":"rabbit"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service, _ := NewService("client", "secret", "custom", WithRedirectURL("https://example.com/callback/custom"))
+	provider, err := NewOAuth2(service, OAuth2Config{AuthURL: server.URL + "/authorize", TokenURL: server.URL + "/token", UserInfoURL: server.URL + "/me"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var identity *Identity
+	var failure error
+	handler := NewHandler("custom", provider, NewMemoryStateStore(0, 0),
+		WithOnSuccess(func(w http.ResponseWriter, r *http.Request, id *Identity, token *Token) {
+			state, _ := LoginStateFromContext(r.Context())
+			identity = id
+			http.Redirect(w, r, state.RedirectTo, http.StatusFound)
+		}),
+		WithOnError(func(w http.ResponseWriter, r *http.Request, err error) {
+			failure = err
+			w.WriteHeader(http.StatusUnauthorized)
+		}),
+	)
+	app := http.NewServeMux()
+	handler.Register(app)
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/login/custom?redirect_to=/profile", nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("unexpected login status %d", recorder.Code)
+	}
+	location, _ := url.Parse(recorder.Header().Get("Location"))
+	state := location.Query().Get("state")
+	challenge = location.Query().Get("code_challenge")
+	if state == "" || challenge == "" {
+		t.Fatalf("unexpected authorization url %s", location)
+	}
+
+	callback := "/callback/custom?" + url.Values{"state": []string{state}, "code": []string{"good"}}.Encode()
+	recorder = httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, callback, nil))
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/profile" || failure != nil {
+		t.Fatalf("unexpected callback status %d %v", recorder.Code, failure)
+	}
+	if identity == nil || identity.Subject != "42" || identity.Name != "rabbit" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	recorder = httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, callback, nil))
+	if !errors.Is(failure, ErrStateNotFound) {
+		t.Fatalf("expected a replayed callback to fail with ErrStateNotFound, got %v", failure)
+	}
+	recorder = httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/callback/custom?error=access_denied&state="+state, nil))
+	var providerErr *ProviderError
+	if !errors.As(failure, &providerErr) || providerErr.Code != "access_denied" {
+		t.Fatalf("expected the provider error, got %v", failure)
+	}
+
+	for _, redirectTo := range []string{"/%09/evil.com", "/%0a/evil.com", "/%5Cevil.com", "//evil.com", "https://evil.com/"} {
+		recorder = httptest.NewRecorder()
+		app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/login/custom?redirect_to="+redirectTo, nil))
+		location, _ = url.Parse(recorder.Header().Get("Location"))
+		if recorder.Code != http.StatusFound {
+			t.Fatalf("unexpected login status %d", recorder.Code)
+		}
+		callback = "/callback/custom?" + url.Values{"state": []string{location.Query().Get("state")}, "code": []string{"good"}}.Encode()
+		challenge = location.Query().Get("code_challenge")
+		recorder = httptest.NewRecorder()
+		app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, callback, nil))
+		if location := recorder.Header().Get("Location"); strings.Contains(location, "evil") {
+			t.Fatalf("expected redirect_to=%s to be dropped, got %q", redirectTo, location)
+		}
+	}
+}
+
+func TestIsLocalURL(t *testing.T) {
+	for u, local := range map[string]bool{
+		"/":                true,
+		"/profile?tab=1":   true,
+		"/\t/evil.com":     false,
+		"/\n/evil.com":     false,
+		"/\\evil.com":      false,
+		"//evil.com":       false,
+		"https://evil.com": false,
+		"evil.com":         false,
+		"":                 false,
+	} {
+		if isLocalURL(u) != local {
+			t.Fatalf("isLocalURL(%q) = %v", u, !local)
+		}
+	}
+}
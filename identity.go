@@ -0,0 +1,75 @@
+package oauth
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Identity is the normalized user returned by every provider after a successful login.
+type Identity struct {
+	// Provider that authenticated the user
+	Provider AuthType `json:"provider"`
+
+	// Subject Unique and stable identifier of the user at the provider
+	Subject string `json:"sub"`
+
+	// Email address of the user, if shared
+	Email string `json:"email,omitempty"`
+
+	// EmailVerified Indicates if the provider verified the email address
+	EmailVerified bool `json:"email_verified,omitempty"`
+
+	// Name Display name of the user
+	Name string `json:"name,omitempty"`
+
+	// Picture Profile picture URL
+	Picture string `json:"picture,omitempty"`
+
+	// Raw Claims or profile fields as returned by the provider
+	Raw map[string]interface{} `json:"raw,omitempty"`
+}
+
+// StringBool is a boolean that also accepts the JSON strings "true" and "false",
+// as sent by some providers for claims such as email_verified.
+type StringBool bool
+
+func (b *StringBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = StringBool(v)
+	case string:
+		*b = StringBool(strings.EqualFold(v, "true"))
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// checkNonce returns an ErrInvalidNonce TokenError when the nonce of an ID token is not the expected one.
+// An empty expected nonce is not checked.
+func checkNonce(provider AuthType, expected, nonce string) error {
+	if expected != "" && nonce != expected {
+		return &TokenError{Provider: provider, Kind: ErrInvalidIdToken, Err: ErrInvalidNonce}
+	}
+	return nil
+}
+
+// rawClaims decodes a JSON document into a generic map, ignoring errors.
+func rawClaims(data []byte) map[string]interface{} {
+	var raw map[string]interface{}
+	_ = json.Unmarshal(data, &raw)
+	return raw
+}
+
+// remarshal converts a decoded JSON value into the typed value out.
+func remarshal(value interface{}, out interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
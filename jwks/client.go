@@ -0,0 +1,329 @@
+package jwks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+// Defaults used by NewClient.
+const (
+	DefaultTTL                = time.Hour
+	DefaultMinRefreshInterval = time.Minute
+	DefaultRefreshTimeout     = 30 * time.Second
+	maxDocumentSize           = 1 << 20
+)
+
+// Fetcher downloads the raw JWKS document from url.
+type Fetcher func(ctx context.Context, url string) ([]byte, error)
+
+// Stats are counters describing the cache activity of a Client.
+type Stats struct {
+	// Refreshes Number of successful key set downloads
+	Refreshes uint64
+
+	// RefreshErrors Number of failed key set downloads
+	RefreshErrors uint64
+
+	// Hits Number of lookups answered from the cache
+	Hits uint64
+
+	// Misses Number of lookups for a key absent from the cache
+	Misses uint64
+
+	// LastRefresh Time of the last successful download
+	LastRefresh time.Time
+}
+
+// Client fetches a key set from a URL and caches it.
+//
+// Keys are refreshed once the TTL elapses, and on a lookup miss (key rotation) at most
+// once per MinRefreshInterval. Concurrent refreshes are deduplicated and a stale set
+// is kept when a refresh fails.
+type Client struct {
+	url                string
+	fetch              Fetcher
+	httpClient         *http.Client
+	ttl                time.Duration
+	minRefreshInterval time.Duration
+	timeout            time.Duration
+	now                func() time.Time
+
+	mu        sync.Mutex
+	set       *Set
+	fetchedAt time.Time
+	attempted time.Time
+	inflight  *call
+
+	refreshes     uint64
+	refreshErrors uint64
+	hits          uint64
+	misses        uint64
+}
+
+// call is a refresh shared by concurrent callers.
+type call struct {
+	done chan struct{}
+	err  error
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used by the default fetcher.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithFetcher replaces the default HTTP fetcher.
+func WithFetcher(fetch Fetcher) Option {
+	return func(c *Client) {
+		c.fetch = fetch
+	}
+}
+
+// WithTTL sets how long a fetched key set is used before it is refreshed.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.ttl = ttl
+	}
+}
+
+// WithMinRefreshInterval sets the minimum delay between refreshes triggered by unknown key IDs.
+func WithMinRefreshInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.minRefreshInterval = interval
+	}
+}
+
+// WithRefreshTimeout sets how long a download of the key set may take, whatever the context of its callers.
+func WithRefreshTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithClock sets the clock used for cache expiry.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// NewClient creates a Client for the key set published at url.
+func NewClient(url string, options ...Option) *Client {
+	c := &Client{
+		url:                url,
+		ttl:                DefaultTTL,
+		minRefreshInterval: DefaultMinRefreshInterval,
+		timeout:            DefaultRefreshTimeout,
+		now:                time.Now,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.fetch == nil {
+		c.fetch = c.fetchHTTP
+	}
+	return c
+}
+
+// URL returns the address of the key set.
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Key returns the key with the given ID usable with alg, refreshing the set when needed.
+func (c *Client) Key(ctx context.Context, kid, alg string) (*Key, error) {
+	set, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := set.Lookup(kid, alg); ok {
+		atomic.AddUint64(&c.hits, 1)
+		return key, nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	// The provider may have rotated its keys: refresh, but not more often than minRefreshInterval.
+	c.mu.Lock()
+	allowed := c.now().Sub(c.attempted) >= c.minRefreshInterval
+	c.mu.Unlock()
+	if allowed {
+		if err = c.Refresh(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := c.cached().Lookup(kid, alg); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+// KeyFunc resolves token verification keys for jwt.Verifier.
+func (c *Client) KeyFunc(ctx context.Context, header jwt.Header) (interface{}, error) {
+	key, err := c.Key(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	return key.Public, nil
+}
+
+// Refresh downloads the key set, sharing the download with concurrent callers.
+// The download is bounded by the refresh timeout of the client, not by ctx: a caller giving up
+// does not fail the refresh for the others, it only stops waiting for it.
+func (c *Client) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	inflight := c.inflight
+	if inflight == nil {
+		inflight = &call{done: make(chan struct{})}
+		c.inflight = inflight
+		c.attempted = c.now()
+		refreshCtx, cancel := context.WithTimeout(detachedContext{ctx}, c.timeout)
+		go func() {
+			defer cancel()
+			c.refresh(refreshCtx, inflight)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-inflight.done:
+		return inflight.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refresh performs the shared download and completes the call.
+func (c *Client) refresh(ctx context.Context, inflight *call) {
+	set, err := c.download(ctx)
+
+	c.mu.Lock()
+	if err != nil {
+		c.refreshErrors++
+	} else {
+		c.set, c.fetchedAt = set, c.now()
+		c.refreshes++
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	inflight.err = err
+	close(inflight.done)
+}
+
+// Start refreshes the key set in the background every TTL until ctx is done.
+func (c *Client) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.ttl)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = c.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Stats returns the cache counters.
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Refreshes:     c.refreshes,
+		RefreshErrors: c.refreshErrors,
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		LastRefresh:   c.fetchedAt,
+	}
+}
+
+// current returns the cached set, refreshing it when it is missing or expired.
+// An expired set is still served if the refresh fails.
+func (c *Client) current(ctx context.Context) (*Set, error) {
+	c.mu.Lock()
+	set, fresh := c.set, c.set != nil && c.now().Sub(c.fetchedAt) < c.ttl
+	c.mu.Unlock()
+	if fresh {
+		return set, nil
+	}
+	if err := c.Refresh(ctx); err != nil {
+		if set != nil {
+			return set, nil
+		}
+		return nil, err
+	}
+	return c.cached(), nil
+}
+
+func (c *Client) cached() *Set {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set
+}
+
+func (c *Client) download(ctx context.Context) (*Set, error) {
+	data, err := c.fetch(ctx, c.url)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// fetchHTTP is the default Fetcher, a plain GET request.
+func (c *Client) fetchHTTP(ctx context.Context, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: fetching %s: status code %d", url, resp.StatusCode)
+	}
+	return data, nil
+}
+
+// detachedContext keeps the values of its parent, e.g. for tracing, without its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,154 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func document(t *testing.T, keys ...JSONWebKey) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParse(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	var keys []JSONWebKey
+	for kid, public := range map[string]interface{}{"rsa": &rsaKey.PublicKey, "p256": &p256.PublicKey, "p384": &p384.PublicKey, "ed": edPublic} {
+		jwk, err := NewJSONWebKey(kid, "", public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, jwk)
+	}
+	keys = append(keys, JSONWebKey{Kty: "oct", Kid: "secret"}, JSONWebKey{Kty: "RSA", Kid: "enc", Use: "enc", N: "AQAB", E: "AQAB"})
+
+	set, err := Parse(document(t, keys...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 4 {
+		t.Fatalf("expected 4 supported keys, got %d", set.Len())
+	}
+	if key, ok := set.Lookup("rsa", "RS256"); !ok || !key.Public.(*rsa.PublicKey).Equal(&rsaKey.PublicKey) {
+		t.Fatal("expected to find the RSA key")
+	}
+	if key, ok := set.Lookup("p384", "ES384"); !ok || !key.Public.(*ecdsa.PublicKey).Equal(&p384.PublicKey) {
+		t.Fatal("expected to find the P-384 key")
+	}
+	if _, ok := set.Lookup("ed", "EdDSA"); !ok {
+		t.Fatal("expected to find the Ed25519 key")
+	}
+	if _, ok := set.Lookup("rsa", "ES256"); ok {
+		t.Fatal("expected the RSA key not to match ES256")
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	jwk, _ := NewJSONWebKey("k1", "RS256", &rsaKey.PublicKey)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write(document(t, jwk))
+	}))
+	defer server.Close()
+
+	now := time.Date(2023, 11, 2, 12, 0, 0, 0, time.UTC)
+	client := NewClient(server.URL, WithTTL(time.Hour), WithClock(func() time.Time { return now }))
+
+	// Concurrent lookups share a single download.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Key(context.Background(), "k1", "RS256"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expected 1 download, got %d", n)
+	}
+
+	// An unknown kid triggers one refresh, then is rate limited.
+	now = now.Add(2 * DefaultMinRefreshInterval)
+	for i := 0; i < 3; i++ {
+		if _, err := client.Key(context.Background(), "rotated", "RS256"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("expected ErrKeyNotFound, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected 2 downloads, got %d", n)
+	}
+
+	// The set is refreshed after the TTL.
+	now = now.Add(2 * time.Hour)
+	if _, err := client.Key(context.Background(), "k1", "RS256"); err != nil {
+		t.Fatal(err)
+	}
+	stats := client.Stats()
+	if stats.Refreshes != 3 || stats.Misses != 3 || stats.Hits != 11 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestClientCanceledCaller(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	jwk, _ := NewJSONWebKey("k1", "RS256", &rsaKey.PublicKey)
+	started, release := make(chan struct{}), make(chan struct{})
+	client := NewClient("https://example.com/jwks", WithFetcher(func(ctx context.Context, url string) ([]byte, error) {
+		close(started)
+		select {
+		case <-release:
+			return document(t, jwk), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- client.Refresh(first)
+	}()
+	<-started
+	second := make(chan error, 1)
+	go func() {
+		_, err := client.Key(context.Background(), "k1", "RS256")
+		second <- err
+	}()
+
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("expected the canceled caller to stop waiting, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("unexpected error of the waiting caller: %v", err)
+	}
+	if stats := client.Stats(); stats.Refreshes != 1 || stats.RefreshErrors != 0 {
+		t.Fatalf("expected the shared download to succeed, got %+v", stats)
+	}
+}
@@ -0,0 +1,240 @@
+// Package jwks fetches, parses and caches JSON Web Key Sets (RFC 7517).
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("jwks: unsupported key")
+	ErrKeyNotFound    = errors.New("jwks: key not found")
+)
+
+// JSONWebKey is the JSON representation of a public key.
+type JSONWebKey struct {
+	// Key type: "RSA", "EC" or "OKP"
+	Kty string `json:"kty"`
+
+	// Key ID
+	Kid string `json:"kid,omitempty"`
+
+	// Key usage, "sig" for signature keys
+	Use string `json:"use,omitempty"`
+
+	// Key algorithm
+	Alg string `json:"alg,omitempty"`
+
+	// RSA modulus
+	N string `json:"n,omitempty"`
+
+	// RSA exponent
+	E string `json:"e,omitempty"`
+
+	// Curve of EC and OKP keys
+	Crv string `json:"crv,omitempty"`
+
+	// X coordinate of EC keys, public key of OKP keys
+	X string `json:"x,omitempty"`
+
+	// Y coordinate of EC keys
+	Y string `json:"y,omitempty"`
+}
+
+// Key is a parsed public key of a set.
+type Key struct {
+	// Key ID
+	Kid string
+
+	// Key type
+	Kty string
+
+	// Key algorithm, empty if the set does not restrict it
+	Alg string
+
+	// Key usage
+	Use string
+
+	// Public is an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+	Public interface{}
+}
+
+// Set is an immutable key set indexed by key ID.
+type Set struct {
+	keys []*Key
+	kids map[string][]*Key
+}
+
+// Parse decodes a JWKS document. Keys of unsupported types or curves are skipped.
+func Parse(data []byte) (*Set, error) {
+	var document struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	set := &Set{kids: make(map[string][]*Key)}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		public, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		key := &Key{Kid: jwk.Kid, Kty: jwk.Kty, Alg: jwk.Alg, Use: jwk.Use, Public: public}
+		set.keys = append(set.keys, key)
+		set.kids[key.Kid] = append(set.kids[key.Kid], key)
+	}
+	return set, nil
+}
+
+// Len returns the number of keys in the set.
+func (s *Set) Len() int {
+	return len(s.keys)
+}
+
+// Keys returns the keys of the set.
+func (s *Set) Keys() []*Key {
+	return append([]*Key(nil), s.keys...)
+}
+
+// Lookup returns the key with the given ID that can be used with alg.
+// An empty kid matches only when the set holds a single candidate.
+func (s *Set) Lookup(kid, alg string) (*Key, bool) {
+	candidates := s.kids[kid]
+	if kid == "" && len(candidates) == 0 {
+		candidates = s.keys
+	}
+	var found *Key
+	for _, key := range candidates {
+		if !key.compatible(alg) {
+			continue
+		}
+		if kid != "" {
+			return key, true
+		}
+		if found != nil {
+			return nil, false
+		}
+		found = key
+	}
+	return found, found != nil
+}
+
+// compatible reports whether the key can verify signatures made with alg.
+func (k *Key) compatible(alg string) bool {
+	if alg == "" {
+		return true
+	}
+	if k.Alg != "" {
+		return k.Alg == alg
+	}
+	switch k.Public.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256" || alg == "RS384" || alg == "RS512" || alg == "PS256" || alg == "PS384" || alg == "PS512"
+	case *ecdsa.PublicKey:
+		return alg == "ES256" || alg == "ES384" || alg == "ES512"
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+// PublicKey converts the JSON representation into a Go public key.
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RSA parameters", ErrUnsupportedKey)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		public := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(public.X, public.Y) {
+			return nil, fmt.Errorf("%w: point is not on curve %s", ErrUnsupportedKey, k.Crv)
+		}
+		return public, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key size", ErrUnsupportedKey)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, k.Kty)
+}
+
+// NewJSONWebKey converts a public key into its JSON representation.
+func NewJSONWebKey(kid, alg string, public interface{}) (JSONWebKey, error) {
+	jwk := JSONWebKey{Kid: kid, Alg: alg, Use: "sig"}
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(key.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.X = encode(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(key)
+	default:
+		return JSONWebKey{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+	return jwk, nil
+}
+
+func decode(value string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKey, err)
+	}
+	return data, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"fmt"
+	"math/big"
+)
+
+// Supported signing algorithms (RFC 7518 and RFC 8037).
+const (
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	PS256 = "PS256"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	HS256 = "HS256"
+	EdDSA = "EdDSA"
+)
+
+// AsymmetricAlgorithms lists every supported public key algorithm.
+// HS256 is left out because it must be enabled explicitly.
+var AsymmetricAlgorithms = []string{RS256, RS384, RS512, PS256, ES256, ES384, EdDSA}
+
+type algorithm interface {
+	sign(input []byte, key interface{}) ([]byte, error)
+	verify(input, signature []byte, key interface{}) error
+}
+
+var algorithms = map[string]algorithm{
+	RS256: rsaPKCS1{crypto.SHA256},
+	RS384: rsaPKCS1{crypto.SHA384},
+	RS512: rsaPKCS1{crypto.SHA512},
+	PS256: rsaPSS{crypto.SHA256},
+	ES256: ecdsaAlg{crypto.SHA256, elliptic.P256()},
+	ES384: ecdsaAlg{crypto.SHA384, elliptic.P384()},
+	HS256: hmacAlg{crypto.SHA256},
+	EdDSA: eddsa{},
+}
+
+// lookup returns the implementation of alg. "none" and unknown algorithms are rejected.
+func lookup(alg string) (algorithm, error) {
+	a, ok := algorithms[alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+	return a, nil
+}
+
+func digest(hash crypto.Hash, input []byte) []byte {
+	h := hash.New()
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+type rsaPKCS1 struct{ hash crypto.Hash }
+
+func (a rsaPKCS1) sign(input []byte, key interface{}) ([]byte, error) {
+	k, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return rsa.SignPKCS1v15(rand.Reader, k, a.hash, digest(a.hash, input))
+}
+
+func (a rsaPKCS1) verify(input, signature []byte, key interface{}) error {
+	k, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return ErrInvalidKey
+	}
+	if err := rsa.VerifyPKCS1v15(k, a.hash, digest(a.hash, input), signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type rsaPSS struct{ hash crypto.Hash }
+
+func (a rsaPSS) sign(input []byte, key interface{}) ([]byte, error) {
+	k, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return rsa.SignPSS(rand.Reader, k, a.hash, digest(a.hash, input), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+}
+
+func (a rsaPSS) verify(input, signature []byte, key interface{}) error {
+	k, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return ErrInvalidKey
+	}
+	err := rsa.VerifyPSS(k, a.hash, digest(a.hash, input), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type ecdsaAlg struct {
+	hash  crypto.Hash
+	curve elliptic.Curve
+}
+
+// size returns the byte length of each of the R and S signature halves.
+func (a ecdsaAlg) size() int {
+	return (a.curve.Params().BitSize + 7) / 8
+}
+
+func (a ecdsaAlg) sign(input []byte, key interface{}) ([]byte, error) {
+	k, ok := key.(*ecdsa.PrivateKey)
+	if !ok || k.Curve != a.curve {
+		return nil, ErrInvalidKey
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, k, digest(a.hash, input))
+	if err != nil {
+		return nil, err
+	}
+	// JWS uses the fixed-size R || S concatenation instead of ASN.1.
+	size := a.size()
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature, nil
+}
+
+func (a ecdsaAlg) verify(input, signature []byte, key interface{}) error {
+	k, ok := key.(*ecdsa.PublicKey)
+	if !ok || k.Curve != a.curve {
+		return ErrInvalidKey
+	}
+	size := a.size()
+	if len(signature) != 2*size {
+		return ErrInvalidSignature
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(k, digest(a.hash, input), r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type hmacAlg struct{ hash crypto.Hash }
+
+func (a hmacAlg) sign(input []byte, key interface{}) ([]byte, error) {
+	k, ok := key.([]byte)
+	if !ok || len(k) == 0 {
+		return nil, ErrInvalidKey
+	}
+	mac := hmac.New(a.hash.New, k)
+	mac.Write(input)
+	return mac.Sum(nil), nil
+}
+
+func (a hmacAlg) verify(input, signature []byte, key interface{}) error {
+	expected, err := a.sign(input, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type eddsa struct{}
+
+func (eddsa) sign(input []byte, key interface{}) ([]byte, error) {
+	k, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.Sign(k, input), nil
+}
+
+func (eddsa) verify(input, signature []byte, key interface{}) error {
+	k, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return ErrInvalidKey
+	}
+	if !ed25519.Verify(k, input, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	ErrExpired         = errors.New("jwt: token is expired")
+	ErrMissingExpiry   = errors.New("jwt: token has no expiry")
+	ErrNotValidYet     = errors.New("jwt: token is not valid yet")
+	ErrIssuedInFuture  = errors.New("jwt: token is issued in the future")
+	ErrInvalidIssuer   = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience = errors.New("jwt: invalid audience")
+)
+
+// NumericDate is a JSON numeric date: seconds since the Unix epoch.
+// Fractional values are truncated.
+type NumericDate int64
+
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	var value json.Number
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	f, err := value.Float64()
+	if err != nil {
+		return err
+	}
+	*d = NumericDate(math.Trunc(f))
+	return nil
+}
+
+// Time returns the date as a time.Time, the zero time if it is unset.
+func (d NumericDate) Time() time.Time {
+	if d == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(d), 0)
+}
+
+// Audience is the "aud" claim, which may be a single string or an array of strings.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Contains reports whether the audience includes any of the given values.
+func (a Audience) Contains(values ...string) bool {
+	for _, aud := range a {
+		for _, value := range values {
+			if aud == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Claims are the registered claims of RFC 7519 section 4.1.
+type Claims struct {
+	// Issuer of the token
+	Issuer string `json:"iss,omitempty"`
+
+	// Subject of the token
+	Subject string `json:"sub,omitempty"`
+
+	// Audience of the token
+	Audience Audience `json:"aud,omitempty"`
+
+	// Expiration time of the token
+	ExpiresAt NumericDate `json:"exp,omitempty"`
+
+	// Time before which the token must not be accepted
+	NotBefore NumericDate `json:"nbf,omitempty"`
+
+	// Issued at time of the token
+	IssuedAt NumericDate `json:"iat,omitempty"`
+
+	// Unique identifier of the token
+	ID string `json:"jti,omitempty"`
+}
+
+// Expected describes the values the registered claims are validated against.
+type Expected struct {
+	// Issuers accepted in "iss", empty to skip the check.
+	Issuers []string
+
+	// Audiences of which at least one must appear in "aud", empty to skip the check.
+	Audiences []string
+
+	// Now is the reference time.
+	Now time.Time
+
+	// Leeway tolerated for clock skew on time based claims.
+	Leeway time.Duration
+
+	// AllowMissingExpiry accepts tokens without an "exp" claim.
+	AllowMissingExpiry bool
+}
+
+// Validate checks the registered claims against the expected values.
+func (c *Claims) Validate(expected Expected) error {
+	now := expected.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if c.ExpiresAt == 0 {
+		if !expected.AllowMissingExpiry {
+			return ErrMissingExpiry
+		}
+	} else if !now.Before(c.ExpiresAt.Time().Add(expected.Leeway)) {
+		return fmt.Errorf("%w: expired at %s", ErrExpired, c.ExpiresAt.Time().UTC().Format(time.RFC3339))
+	}
+	if c.NotBefore != 0 && now.Add(expected.Leeway).Before(c.NotBefore.Time()) {
+		return ErrNotValidYet
+	}
+	if c.IssuedAt != 0 && now.Add(expected.Leeway).Before(c.IssuedAt.Time()) {
+		return ErrIssuedInFuture
+	}
+
+	if len(expected.Issuers) > 0 {
+		valid := false
+		for _, issuer := range expected.Issuers {
+			if c.Issuer == issuer {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%w: %q", ErrInvalidIssuer, c.Issuer)
+		}
+	}
+	if len(expected.Audiences) > 0 && !c.Audience.Contains(expected.Audiences...) {
+		return fmt.Errorf("%w: %v", ErrInvalidAudience, []string(c.Audience))
+	}
+	return nil
+}
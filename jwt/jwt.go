@@ -0,0 +1,132 @@
+// Package jwt parses, signs and verifies compact JWS tokens (JSON Web Tokens)
+// as used by the ID tokens of the social login providers.
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMalformed            = errors.New("jwt: malformed token")
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+	ErrAlgorithmNotAllowed  = errors.New("jwt: algorithm not allowed")
+	ErrInvalidKey           = errors.New("jwt: key does not match algorithm")
+	ErrInvalidSignature     = errors.New("jwt: invalid signature")
+	ErrUnsupportedCritical  = errors.New("jwt: unsupported critical header")
+)
+
+// Header is the JOSE header of a token.
+type Header struct {
+	// Algorithm used to sign the token
+	Alg string `json:"alg"`
+
+	// Key ID used to select the verification key
+	Kid string `json:"kid,omitempty"`
+
+	// Media type of the token, usually "JWT"
+	Typ string `json:"typ,omitempty"`
+
+	// Content type, set for nested tokens
+	Cty string `json:"cty,omitempty"`
+
+	// Header parameters that must be understood by the recipient
+	Crit []string `json:"crit,omitempty"`
+}
+
+// Token is a parsed, not yet verified, compact JWS.
+type Token struct {
+	// Raw compact serialization of the token
+	Raw string
+
+	// Header decoded JOSE header
+	Header Header
+
+	// Payload decoded claims set
+	Payload []byte
+
+	// Signature decoded signature bytes
+	Signature []byte
+}
+
+// Parse decodes a compact JWS without verifying its signature.
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 segments, got %d", ErrMalformed, len(parts))
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrMalformed, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+
+	token := &Token{Raw: raw, Payload: payload, Signature: signature}
+	if err = json.Unmarshal(headerBytes, &token.Header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	if token.Header.Alg == "" {
+		return nil, fmt.Errorf("%w: missing alg", ErrMalformed)
+	}
+	if len(token.Header.Crit) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedCritical, token.Header.Crit)
+	}
+	return token, nil
+}
+
+// signingInput returns the header and payload segments the signature is computed over.
+func (t *Token) signingInput() string {
+	return t.Raw[:strings.LastIndexByte(t.Raw, '.')]
+}
+
+// Claims decodes the payload into v.
+func (t *Token) Claims(v interface{}) error {
+	if err := json.Unmarshal(t.Payload, v); err != nil {
+		return fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	return nil
+}
+
+// VerifySignature checks the token signature with the given key.
+// The key type must match the algorithm family of the header, which prevents
+// algorithm confusion (for instance an RSA public key used as an HMAC secret).
+func (t *Token) VerifySignature(key interface{}) error {
+	alg, err := lookup(t.Header.Alg)
+	if err != nil {
+		return err
+	}
+	return alg.verify([]byte(t.signingInput()), t.Signature, key)
+}
+
+// Sign creates a compact JWS for the given claims. key is an *rsa.PrivateKey,
+// *ecdsa.PrivateKey, ed25519.PrivateKey or []byte secret depending on alg.
+func Sign(alg, kid string, claims interface{}, key interface{}) (string, error) {
+	algorithm, err := lookup(alg)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(Header{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := algorithm.sign([]byte(input), key)
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
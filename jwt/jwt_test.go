@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2023, 11, 2, 12, 0, 0, 0, time.UTC)
+
+func claims() Claims {
+	return Claims{
+		Issuer:    "https://appleid.apple.com",
+		Subject:   "001597.3efce279e74849ce936f38b726a9b3e5.0345",
+		Audience:  Audience{"com.short.roll"},
+		IssuedAt:  NumericDate(now.Add(-time.Minute).Unix()),
+		ExpiresAt: NumericDate(now.Add(time.Hour).Unix()),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	secret := []byte("secret")
+
+	cases := []struct {
+		alg     string
+		private interface{}
+		public  interface{}
+	}{
+		{RS256, rsaKey, &rsaKey.PublicKey},
+		{RS384, rsaKey, &rsaKey.PublicKey},
+		{RS512, rsaKey, &rsaKey.PublicKey},
+		{PS256, rsaKey, &rsaKey.PublicKey},
+		{ES256, p256, &p256.PublicKey},
+		{ES384, p384, &p384.PublicKey},
+		{HS256, secret, secret},
+		{EdDSA, edPrivate, edPublic},
+	}
+	for _, c := range cases {
+		raw, err := Sign(c.alg, "kid-1", claims(), c.private)
+		if err != nil {
+			t.Fatalf("%s: %v", c.alg, err)
+		}
+		verifier := &Verifier{
+			Algorithms: []string{c.alg},
+			Keys:       StaticKey(c.public),
+			Issuers:    []string{"https://appleid.apple.com"},
+			Audiences:  []string{"com.short.roll"},
+			Clock:      func() time.Time { return now },
+		}
+		var decoded Claims
+		token, err := verifier.Verify(context.Background(), raw, &decoded)
+		if err != nil {
+			t.Fatalf("%s: %v", c.alg, err)
+		}
+		if token.Header.Kid != "kid-1" || decoded.Subject != claims().Subject {
+			t.Fatalf("%s: unexpected token %+v %+v", c.alg, token.Header, decoded)
+		}
+
+		tampered := raw[:len(raw)-4] + "AAAA"
+		if _, err = verifier.Verify(context.Background(), tampered, nil); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%s: expected ErrInvalidSignature, got %v", c.alg, err)
+		}
+	}
+}
+
+func TestRejectNoneAndConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x"}`)) + "."
+	verifier := &Verifier{Algorithms: []string{"none"}, Keys: StaticKey(&rsaKey.PublicKey)}
+	if _, err = verifier.Verify(context.Background(), none, nil); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+
+	// An HS256 token signed with the RSA public key bytes must not verify against the public key.
+	publicBytes := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+	raw, err := Sign(HS256, "", claims(), publicBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier = &Verifier{Keys: StaticKey(&rsaKey.PublicKey), Clock: func() time.Time { return now }}
+	if _, err = verifier.Verify(context.Background(), raw, nil); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("expected ErrAlgorithmNotAllowed, got %v", err)
+	}
+	verifier.Algorithms = []string{HS256}
+	if _, err = verifier.Verify(context.Background(), raw, nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	c := claims()
+	if err := c.Validate(Expected{Now: now.Add(2 * time.Hour)}); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if err := c.Validate(Expected{Now: now.Add(61 * time.Minute), Leeway: 5 * time.Minute}); err != nil {
+		t.Fatalf("expected leeway to accept the token, got %v", err)
+	}
+	if err := c.Validate(Expected{Now: now, Audiences: []string{"other"}}); !errors.Is(err, ErrInvalidAudience) {
+		t.Fatalf("expected ErrInvalidAudience, got %v", err)
+	}
+	if err := c.Validate(Expected{Now: now, Issuers: []string{"https://accounts.google.com"}}); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("expected ErrInvalidIssuer, got %v", err)
+	}
+
+	var a Audience
+	if err := a.UnmarshalJSON([]byte(`["a","b"]`)); err != nil || !a.Contains("b") {
+		t.Fatalf("unexpected audience %v %v", a, err)
+	}
+}
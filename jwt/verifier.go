@@ -0,0 +1,109 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNoKey = errors.New("jwt: no verification key")
+
+// KeyFunc returns the verification key for a token header, typically looked up by its "kid".
+type KeyFunc func(ctx context.Context, header Header) (interface{}, error)
+
+// StaticKey returns a KeyFunc that always returns key.
+func StaticKey(key interface{}) KeyFunc {
+	return func(context.Context, Header) (interface{}, error) {
+		return key, nil
+	}
+}
+
+// Verifier verifies the signature and registered claims of tokens.
+type Verifier struct {
+	// Algorithms accepted in the header. Defaults to AsymmetricAlgorithms.
+	Algorithms []string
+
+	// Keys resolves the verification key of a token.
+	Keys KeyFunc
+
+	// Issuers accepted in "iss", empty to skip the check.
+	Issuers []string
+
+	// Audiences of which at least one must appear in "aud", empty to skip the check.
+	Audiences []string
+
+	// Leeway tolerated for clock skew on time based claims.
+	Leeway time.Duration
+
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+
+	// AllowMissingExpiry accepts tokens without an "exp" claim.
+	AllowMissingExpiry bool
+}
+
+// Verify parses raw, checks its algorithm, signature and registered claims, and
+// decodes the payload into claims when it is not nil.
+func (v *Verifier) Verify(ctx context.Context, raw string, claims interface{}) (*Token, error) {
+	token, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !v.allowed(token.Header.Alg) {
+		return nil, fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, token.Header.Alg)
+	}
+	if v.Keys == nil {
+		return nil, ErrNoKey
+	}
+	key, err := v.Keys(ctx, token.Header)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrNoKey
+	}
+	if err = token.VerifySignature(key); err != nil {
+		return nil, err
+	}
+
+	var registered Claims
+	if err = token.Claims(&registered); err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if v.Clock != nil {
+		now = v.Clock
+	}
+	err = registered.Validate(Expected{
+		Issuers:            v.Issuers,
+		Audiences:          v.Audiences,
+		Now:                now(),
+		Leeway:             v.Leeway,
+		AllowMissingExpiry: v.AllowMissingExpiry,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims != nil {
+		if err = token.Claims(claims); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// allowed reports whether alg is accepted by the verifier.
+func (v *Verifier) allowed(alg string) bool {
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = AsymmetricAlgorithms
+	}
+	for _, a := range algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+const (
+	KakaoAuthEndpoint       = "https://kauth.kakao.com"
+	KakaoAPIEndpoint        = "https://kapi.kakao.com"
+	KakaoURLAuthorize       = KakaoAuthEndpoint + "/oauth/authorize"
+	KakaoURLToken           = KakaoAuthEndpoint + "/oauth/token"
+	KakaoURLKeys            = KakaoAuthEndpoint + "/.well-known/jwks.json"
+	KakaoURLUserMe          = KakaoAPIEndpoint + "/v2/user/me"
+	KakaoURLAccessTokenInfo = KakaoAPIEndpoint + "/v1/user/access_token_info"
+	KakaoURLLogout          = KakaoAPIEndpoint + "/v1/user/logout"
+	KakaoURLUnlink          = KakaoAPIEndpoint + "/v1/user/unlink"
+)
+
+// KakaoClaims struct represents the claims in a Kakao ID Token.
+type KakaoClaims struct {
+	// Expiration time of the token
+	Exp int64 `json:"exp"`
+
+	// Issued at time of the token
+	Iat int64 `json:"iat"`
+
+	// Time the user authenticated
+	AuthTime int64 `json:"auth_time"`
+
+	// Issuer of the token, KakaoAuthEndpoint
+	Iss string `json:"iss"`
+
+	// Audience of the token, the REST API key of the application
+	Aud string `json:"aud"`
+
+	// Subject of the token, the Kakao user ID
+	Sub string `json:"sub"`
+
+	// Nickname of the user, with the profile_nickname consent
+	Nickname string `json:"nickname"`
+
+	// Profile picture URL, with the profile_image consent
+	Picture string `json:"picture"`
+
+	// Email address of the user, with the account_email consent
+	Email string `json:"email"`
+
+	// Nonce sent in the authorization request
+	Nonce string `json:"nonce"`
+}
+
+// Identity maps the claims into an Identity. Kakao ID tokens do not tell whether the email address is verified,
+// use Identify with the access token to get it from /v2/user/me.
+func (c *KakaoClaims) Identity() *Identity {
+	return &Identity{
+		Provider: AuthKakao,
+		Subject:  c.Sub,
+		Email:    c.Email,
+		Name:     c.Nickname,
+		Picture:  c.Picture,
+	}
+}
+
+// KakaoUser struct represents the user returned by /v2/user/me.
+type KakaoUser struct {
+	// ID Kakao user ID
+	ID int64 `json:"id"`
+
+	// ConnectedAt Time the user connected to the application
+	ConnectedAt time.Time `json:"connected_at"`
+
+	// KakaoAccount Kakao account information the user consented to share
+	KakaoAccount struct {
+		// Profile of the user
+		Profile struct {
+			Nickname          string `json:"nickname"`
+			ProfileImageURL   string `json:"profile_image_url"`
+			ThumbnailImageURL string `json:"thumbnail_image_url"`
+		} `json:"profile"`
+
+		// Email address of the user
+		Email string `json:"email"`
+
+		// HasEmail Indicates if the account has an email address
+		HasEmail bool `json:"has_email"`
+
+		// IsEmailValid Indicates if the email address is still valid, it may have been used by another account
+		IsEmailValid bool `json:"is_email_valid"`
+
+		// IsEmailVerified Indicates if Kakao verified the email address
+		IsEmailVerified bool `json:"is_email_verified"`
+
+		// EmailNeedsAgreement Indicates if the user must consent before the email address is shared
+		EmailNeedsAgreement bool `json:"email_needs_agreement"`
+	} `json:"kakao_account"`
+
+	// Raw Fields as returned by Kakao
+	Raw map[string]interface{} `json:"-"`
+}
+
+// KakaoTokenInfo struct represents the access token information returned by /v1/user/access_token_info.
+type KakaoTokenInfo struct {
+	// ID Kakao user ID
+	ID int64 `json:"id"`
+
+	// ExpiresIn Remaining lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expires_in"`
+
+	// AppID ID of the application the token was issued to
+	AppID int64 `json:"app_id"`
+}
+
+// Kakao struct represents the Kakao Login provider.
+type Kakao struct {
+	service *Service
+	keys    *jwks.Client
+}
+
+// NewKakao creates a new instance of the Kakao Login provider, the client ID being the REST API key of the application.
+// The kauth.kakao.com URLs are derived from the Endpoint of the service, KakaoAuthEndpoint by default,
+// the kapi.kakao.com URLs are only changed by Endpoints.
+func NewKakao(service *Service) *Kakao {
+	if service.Endpoint == "" {
+		service.Endpoint = KakaoAuthEndpoint
+	}
+	return &Kakao{service: service, keys: service.keys(AuthKakao, service.url(EndpointKeys, KakaoAuthEndpoint, KakaoURLKeys))}
+}
+
+// url returns the URL of the named endpoint, fallback being its default.
+func (p *Kakao) url(name, fallback string) string {
+	return p.service.url(name, KakaoAuthEndpoint, fallback)
+}
+
+// apiURL returns the URL of the named kapi.kakao.com endpoint, which does not follow the Endpoint of the service.
+func (p *Kakao) apiURL(name, fallback string) string {
+	if u := p.service.Endpoints[name]; u != "" {
+		return u
+	}
+	return fallback
+}
+
+// AuthCodeURL builds the Kakao Login authorization URL, requesting the openid, profile_nickname, profile_image
+// and account_email consents by default.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#request-code
+func (p *Kakao) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.url(EndpointAuthorize, KakaoURLAuthorize), authDefaults{
+		scopes:    []string{"openid", "profile_nickname", "profile_image", "account_email"},
+		separator: ",",
+		nonce:     true,
+	}, options...)
+}
+
+// Exchange exchanges an authorization code for tokens at kauth.kakao.com.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#request-token
+func (p *Kakao) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	return p.service.token(ctx, AuthKakao, p.url(EndpointToken, KakaoURLToken), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
+}
+
+// Refresh gets a new access token using a refresh token. Kakao only returns a new refresh token
+// when the current one expires within a month.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#refresh-token
+func (p *Kakao) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, AuthKakao, p.url(EndpointToken, KakaoURLToken), AuthStylePost, refreshToken)
+}
+
+// IDToken verifies a Kakao ID token: its signature against Kakao's published keys, issuer, audience and expiry.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#oidc-id-token-verify
+func (p *Kakao) IDToken(ctx context.Context, token string) (*KakaoClaims, error) {
+	if token == "" {
+		return nil, ErrInvalidIdToken
+	}
+	verifier := &jwt.Verifier{
+		Algorithms: []string{jwt.RS256},
+		Keys:       keyFunc(AuthKakao, p.keys),
+		Issuers:    []string{KakaoAuthEndpoint},
+		Audiences:  p.service.audiences(),
+	}
+	claims := &KakaoClaims{}
+	if _, err := verifier.Verify(ctx, token, claims); err != nil {
+		return nil, tokenError(AuthKakao, err)
+	}
+	return claims, nil
+}
+
+// UserMe gets the user and the Kakao account information they consented to share.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#req-user-info
+func (p *Kakao) UserMe(ctx context.Context, accessToken string) (*KakaoUser, error) {
+	var raw json.RawMessage
+	if err := p.bearer(ctx, http.MethodGet, p.apiURL(EndpointProfile, KakaoURLUserMe), accessToken, &raw); err != nil {
+		return nil, err
+	}
+	user := &KakaoUser{}
+	if err := json.Unmarshal(raw, user); err != nil {
+		return nil, err
+	}
+	user.Raw = rawClaims(raw)
+	return user, nil
+}
+
+// Identify returns the user of a code exchange from /v2/user/me, verifying the ID token first when
+// the openid scope was granted. The email address is verified when Kakao reports it both valid and verified.
+// Without an access token, the user is read from the ID token alone.
+func (p *Kakao) Identify(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	var claims *KakaoClaims
+	if token.IdToken != "" {
+		var err error
+		if claims, err = p.IDToken(ctx, token.IdToken); err != nil {
+			return nil, err
+		}
+		if err = checkNonce(AuthKakao, nonce, claims.Nonce); err != nil {
+			return nil, err
+		}
+	}
+	if token.AccessToken == "" && claims != nil {
+		return claims.Identity(), nil
+	}
+	user, err := p.UserMe(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	subject := strconv.FormatInt(user.ID, 10)
+	if claims != nil && claims.Sub != subject {
+		return nil, &TokenError{Provider: AuthKakao, Kind: ErrInvalidIdToken, Err: fmt.Errorf("subject %q does not match user %s", claims.Sub, subject)}
+	}
+	account := &user.KakaoAccount
+	return &Identity{
+		Provider:      AuthKakao,
+		Subject:       subject,
+		Email:         account.Email,
+		EmailVerified: account.Email != "" && account.IsEmailValid && account.IsEmailVerified,
+		Name:          account.Profile.Nickname,
+		Picture:       account.Profile.ProfileImageURL,
+		Raw:           user.Raw,
+	}, nil
+}
+
+// AccessTokenInfo gets the user, application and remaining lifetime of an access token.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#get-token-info
+func (p *Kakao) AccessTokenInfo(ctx context.Context, accessToken string) (*KakaoTokenInfo, error) {
+	info := &KakaoTokenInfo{}
+	if err := p.bearer(ctx, http.MethodGet, p.apiURL(EndpointVerify, KakaoURLAccessTokenInfo), accessToken, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Logout expires the access and refresh tokens of the user and returns their ID.
+// The user stays logged in to Kakao Accounts.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#logout
+func (p *Kakao) Logout(ctx context.Context, accessToken string) (int64, error) {
+	var data struct {
+		ID int64 `json:"id"`
+	}
+	if err := p.bearer(ctx, http.MethodPost, p.apiURL(EndpointRevoke, KakaoURLLogout), accessToken, &data); err != nil {
+		return 0, err
+	}
+	return data.ID, nil
+}
+
+// Unlink disconnects the user from the application, expiring every token, and returns their ID.
+//
+// documentation https://developers.kakao.com/docs/latest/en/kakaologin/rest-api#unlink
+func (p *Kakao) Unlink(ctx context.Context, accessToken string) (int64, error) {
+	var data struct {
+		ID int64 `json:"id"`
+	}
+	if err := p.bearer(ctx, http.MethodPost, p.apiURL(EndpointUnlink, KakaoURLUnlink), accessToken, &data); err != nil {
+		return 0, err
+	}
+	return data.ID, nil
+}
+
+// bearer performs a request to the Kakao API authorized with the user's access token and decodes the JSON response into out.
+func (p *Kakao) bearer(ctx context.Context, method, u, accessToken string, out interface{}) error {
+	if accessToken == "" {
+		return ErrInvalidAccessToken
+	}
+	return p.service.request(u, method,
+		WithTimeout(30*time.Second),
+		WithHeader(http.Header{"Authorization": []string{"Bearer " + accessToken}}),
+		WithErrorDecoder(providerErrors(AuthKakao, ErrInvalidAccessToken)),
+	).DoJSON(ctx, out)
+}
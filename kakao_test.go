@@ -0,0 +1,99 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"socialgoauth.org/social-goauth/oauthtest"
+)
+
+func TestKakao(t *testing.T) {
+	server := oauthtest.NewKakao("8f1d2c3b4a5e6f708192a3b4c5d6e7f8", "secret")
+	defer server.Close()
+	redirectURL := "https://example.com/callback/kakao"
+	service, err := NewService(server.ClientID, server.ClientSecret, AuthKakao, WithRedirectURL(redirectURL), WithTransport(server.Transport()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	kakao := NewKakao(service)
+	ctx := context.Background()
+
+	request, err := kakao.AuthCodeURL()
+	if nil != err {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(request.URL)
+	if u.Path != "/oauth/authorize" || u.Query().Get("scope") != "openid,profile_nickname,profile_image,account_email" || request.Nonce == "" {
+		t.Fatalf("unexpected authorization url %s", request.URL)
+	}
+
+	token, err := kakao.Exchange(ctx, server.Code(redirectURL, request.Nonce, ""), "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	identity, err := kakao.Identify(ctx, token, request.Nonce)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if identity.Subject != "3141592653" || identity.Email != "rabbit@example.com" || !identity.EmailVerified || identity.Name != "Rabbit" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	if _, err = kakao.Identify(ctx, token, "other"); !errors.Is(err, ErrInvalidIdToken) {
+		t.Fatalf("expected ErrInvalidIdToken for a nonce mismatch, got %v", err)
+	}
+	if identity, err = kakao.Identify(ctx, &Token{IdToken: token.IdToken}, ""); nil != err || identity.Subject != "3141592653" || identity.EmailVerified {
+		t.Fatalf("unexpected identity from the ID token %+v: %v", identity, err)
+	}
+
+	info, err := kakao.AccessTokenInfo(ctx, token.AccessToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if info.ID != 3141592653 || info.AppID != oauthtest.KakaoAppID || info.ExpiresIn <= 0 {
+		t.Fatalf("unexpected token info %+v", info)
+	}
+
+	refreshed, err := kakao.Refresh(ctx, token.RefreshToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken != token.RefreshToken {
+		t.Fatalf("expected the refresh token to be kept, got %q", refreshed.RefreshToken)
+	}
+
+	_, err = kakao.Exchange(ctx, "bad", "")
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Code != "invalid_grant" || !errors.Is(err, ErrInvalidIdCode) {
+		t.Fatalf("expected invalid_grant, got %v", err)
+	}
+
+	if id, err := kakao.Logout(ctx, refreshed.AccessToken); nil != err || id != 3141592653 {
+		t.Fatalf("unexpected logout %d: %v", id, err)
+	}
+	_, err = kakao.UserMe(ctx, refreshed.AccessToken)
+	if !errors.As(err, &providerErr) || providerErr.ErrorCode != -401 || !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("expected ErrInvalidAccessToken after logout, got %v", err)
+	}
+
+	token, err = kakao.Exchange(ctx, server.Code(redirectURL, "", ""), "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if id, err := kakao.Unlink(ctx, token.AccessToken); nil != err || id != 3141592653 {
+		t.Fatalf("unexpected unlink %d: %v", id, err)
+	}
+	if _, err = kakao.Refresh(ctx, token.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken after unlink, got %v", err)
+	}
+
+	service, _ = NewService("client", "secret", AuthKakao, WithEndpoint("https://kauth.example.com"))
+	kakao = NewKakao(service)
+	if u := kakao.apiURL(EndpointProfile, KakaoURLUserMe); u != KakaoURLUserMe {
+		t.Fatalf("expected the API to stay on %s, got %s", KakaoAPIEndpoint, u)
+	}
+	if u := kakao.url(EndpointToken, KakaoURLToken); u != "https://kauth.example.com/oauth/token" {
+		t.Fatalf("unexpected token url %s", u)
+	}
+}
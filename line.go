@@ -1,12 +1,13 @@
 package oauth
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"time"
+
+	"socialgoauth.org/social-goauth/jwt"
 )
 
 const (
@@ -19,6 +20,7 @@ const (
 	LineURLUserInformation    = LineBaseEndpoint + "/oauth2/v2.1/userinfo"
 	LineURLProfile            = LineBaseEndpoint + "/v2/profile"
 	LineURLFriendshipStatus   = LineBaseEndpoint + "/friendship/v1/status"
+	LineURLAuthorize          = "https://access.line.me/oauth2/v2.1/authorize"
 )
 
 type LineAccessToken struct {
@@ -31,7 +33,7 @@ type LineAccessToken struct {
 }
 
 type LineAccessTokenVerification struct {
-	Scope     string `json:"profile"`
+	Scope     string `json:"scope"`
 	ClientId  string `json:"client_id"`
 	ExpiresIn int64  `json:"expires_in"`
 }
@@ -58,7 +60,7 @@ type LineUserInformation struct {
 type LineUserProfile struct {
 	UserId        string `json:"userId"`
 	DisplayName   string `json:"displayName"`
-	PictureUrl    int64  `json:"pictureUrl"`
+	PictureUrl    string `json:"pictureUrl"`
 	StatusMessage string `json:"statusMessage"`
 }
 
@@ -66,11 +68,71 @@ type Line struct {
 	service *Service
 }
 
+// NewLine creates a new instance of the LINE Login provider.
+// URLs are derived from the Endpoint of the service, LineBaseEndpoint by default.
 func NewLine(service *Service) *Line {
-	service.Endpoint = LineBaseEndpoint
+	if service.Endpoint == "" {
+		service.Endpoint = LineBaseEndpoint
+	}
 	return &Line{service: service}
 }
 
+// url returns the URL of the named endpoint, fallback being its default.
+func (p *Line) url(name, fallback string) string {
+	return p.service.url(name, LineBaseEndpoint, fallback)
+}
+
+// AuthCodeURL builds the LINE Login authorization URL, requesting the profile, openid and email scopes by default.
+//
+// documentation https://developers.line.biz/en/docs/line-login/integrate-line-login/#making-an-authorization-request
+func (p *Line) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.url(EndpointAuthorize, LineURLAuthorize), authDefaults{
+		scopes: []string{"profile", "openid", "email"},
+		nonce:  true,
+	}, options...)
+}
+
+// Exchange Issues an access token from the authorization code received in the callback.
+//
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+//
+// documentation https://developers.line.biz/en/reference/line-login/#issue-access-token
+func (p *Line) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if "" == code {
+		return nil, ErrInvalidIdCode
+	}
+	return p.service.token(ctx, AuthLine, p.url(EndpointToken, LineURLAccessToken), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
+}
+
+// Identify returns the user of a code exchange, from the ID token when the openid scope was granted
+// and from the user profile otherwise.
+func (p *Line) Identify(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	if token.IdToken == "" {
+		profile, err := p.UserProfile(token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{Provider: AuthLine, Subject: profile.UserId, Name: profile.DisplayName, Picture: profile.PictureUrl}, nil
+	}
+	claims, err := p.IDToken(token.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkNonce(AuthLine, nonce, claims.Nonce); err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Provider: AuthLine,
+		Subject:  claims.Sub,
+		Email:    claims.Email,
+		Name:     claims.Name,
+		Picture:  claims.Picture,
+	}
+	_ = remarshal(claims, &identity.Raw)
+	return identity, nil
+}
+
 // AccessToken Verifies if an access token is valid.
 //
 // For general recommendations on how to securely handle user registration and login with access tokens,
@@ -85,23 +147,12 @@ func (p *Line) AccessToken(accessToken string) (*LineAccessTokenVerification, er
 	if "" == accessToken {
 		return nil, ErrInvalidAccessToken
 	}
-	u := fmt.Sprintf("%s?access_token=%s", LineURLVerifyAccessToken, accessToken)
-	resp, err := New(u, http.MethodGet, p.service.ProxyURL).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	value, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	u := p.url(EndpointVerify, LineURLVerifyAccessToken) + "?" + url.Values{"access_token": []string{accessToken}}.Encode()
 	// If the access token has expired, a 400 Bad Request HTTP status code and a JSON response are returned
-	if http.StatusOK != resp.StatusCode {
-		return nil, fmt.Errorf("the status code is : %d", resp.StatusCode)
-	}
-	fmt.Println(string(value))
 	data := &LineAccessTokenVerification{}
-	err = json.Unmarshal(value, &data)
+	err := p.service.request(u, http.MethodGet,
+		WithErrorDecoder(providerErrors(AuthLine, ErrInvalidAccessToken)),
+	).DoJSON(context.Background(), data)
 	if err != nil {
 		return nil, err
 	}
@@ -127,34 +178,26 @@ func (p *Line) RefreshAccessToken(RefreshToken string) (*LineAccessToken, error)
 		"client_id":     []string{p.service.ClientID},
 		"client_secret": []string{p.service.ClientSecret},
 	}
-	header := http.Header{
-		"Content-Type": []string{"application/x-www-form-urlencoded"},
-	}
-	resp, err := New(LineURLRefreshAccessToken, http.MethodPost, p.service.ProxyURL,
+	data := &LineAccessToken{}
+	err := p.service.request(p.url(EndpointToken, LineURLRefreshAccessToken), http.MethodPost,
+		WithContentType(ContentTypeWWWForm),
 		WithData(params),
-		WithHeader(header),
 		WithTimeout(30*time.Second),
-	).Post()
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	value, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	if http.StatusOK != resp.StatusCode {
-		return nil, fmt.Errorf("the status code is : %d", resp.StatusCode)
-	}
-	fmt.Println(string(value))
-	data := &LineAccessToken{}
-	err = json.Unmarshal(value, &data)
+		WithErrorDecoder(providerErrors(AuthLine, ErrInvalidRefreshToken)),
+	).DoJSON(context.Background(), data)
 	if err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
+// Refresh Gets a new access token using a refresh token, as RefreshAccessToken with the normalized Token.
+//
+// documentation https://developers.line.biz/en/reference/line-login/#refresh-access-token
+func (p *Line) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, AuthLine, p.url(EndpointToken, LineURLRefreshAccessToken), AuthStylePost, refreshToken)
+}
+
 // RevokeAccessToken Invalidates a user's access token.
 //
 // Note:
@@ -167,26 +210,20 @@ func (p *Line) RevokeAccessToken(accessToken string) (bool, error) {
 	if "" == accessToken {
 		return false, ErrInvalidAccessToken
 	}
-	header := http.Header{
-		"Content-Type": []string{"application/x-www-form-urlencoded"},
-	}
 	params := url.Values{
 		"access_token": []string{accessToken},
 		"client_id":    []string{p.service.ClientID},
 		// "client_secret": []string{o.ClientSecret},
 	}
-	resp, err := New(LineURLRevokeAccessToken, http.MethodPost, p.service.ProxyURL,
+	err := p.service.request(p.url(EndpointRevoke, LineURLRevokeAccessToken), http.MethodPost,
+		WithContentType(ContentTypeWWWForm),
 		WithData(params),
-		WithHeader(header),
 		WithTimeout(30*time.Second),
-	).Post()
+		WithErrorDecoder(providerErrors(AuthLine, ErrInvalidAccessToken)),
+	).DoJSON(context.Background(), nil)
 	if err != nil {
 		return false, err
 	}
-	defer resp.Body.Close()
-	if http.StatusOK != resp.StatusCode {
-		return false, fmt.Errorf("the status code is : %d", resp.StatusCode)
-	}
 	return true, nil
 }
 
@@ -200,34 +237,41 @@ func (p *Line) IDToken(idToken string) (*LineIDToken, error) {
 	if "" == idToken {
 		return nil, ErrInvalidIdToken
 	}
-	header := http.Header{
-		"Content-Type": []string{"application/x-www-form-urlencoded"},
-	}
 	params := url.Values{
 		"id_token":  []string{idToken},
-		"client_id": []string{p.service.ClientID},
+		"client_id": []string{p.clientID(idToken)},
 	}
-	resp, err := New(LineURLVerifyIDToken, http.MethodPost, p.service.ProxyURL,
+	data := &LineIDToken{}
+	err := p.service.request(p.url(EndpointVerify, LineURLVerifyIDToken), http.MethodPost,
+		WithContentType(ContentTypeWWWForm),
 		WithData(params),
-		WithHeader(header),
 		WithTimeout(30*time.Second),
-	).Post()
+		WithIdempotent(),
+		WithErrorDecoder(providerErrors(AuthLine, ErrInvalidIdToken)),
+	).DoJSON(context.Background(), data)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	value, err := io.ReadAll(resp.Body)
+	return data, nil
+}
+
+// clientID returns the channel the ID token was issued for when it is one of the accepted audiences,
+// since LINE verifies the ID token against a single channel. ClientID is used otherwise.
+func (p *Line) clientID(idToken string) string {
+	parsed, err := jwt.Parse(idToken)
 	if err != nil {
-		return nil, err
+		return p.service.ClientID
 	}
-	fmt.Println(string(value))
-	data := &LineIDToken{}
-	err = json.Unmarshal(value, &data)
-	if err != nil {
-		return nil, err
+	claims := &jwt.Claims{}
+	if err = parsed.Claims(claims); err != nil {
+		return p.service.ClientID
 	}
-
-	return data, nil
+	for _, audience := range p.service.audiences() {
+		if claims.Audience.Contains(audience) {
+			return audience
+		}
+	}
+	return p.service.ClientID
 }
 
 // UserInformation Gets a user's ID, display name, and profile image.
@@ -243,32 +287,10 @@ func (p *Line) UserInformation(accessToken string) (*LineUserInformation, error)
 	if "" == accessToken {
 		return nil, ErrInvalidAccessToken
 	}
-	//_, err := p.VerifyAccessToken(accessToken)
-	//if nil != err {
-	//	return nil, err
-	//}
-	header := http.Header{
-		"Authorization": []string{fmt.Sprintf("Bearer %s", accessToken)},
-	}
-	resp, err := New(LineURLProfile, http.MethodGet, p.service.ProxyURL, WithTimeout(30*time.Second), WithHeader(header)).Get()
-	if err != nil {
-		return nil, err
-	}
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	value, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Println(string(value))
 	data := &LineUserInformation{}
-	err = json.Unmarshal(value, &data)
-	if err != nil {
+	if err := p.bearer(p.url(EndpointUserInfo, LineURLUserInformation), accessToken, data); err != nil {
 		return nil, err
 	}
-
 	return data, nil
 }
 
@@ -284,25 +306,10 @@ func (p *Line) UserProfile(accessToken string) (*LineUserProfile, error) {
 	if "" == accessToken {
 		return nil, ErrInvalidAccessToken
 	}
-	header := http.Header{
-		"Authorization": []string{fmt.Sprintf("Bearer %s", accessToken)},
-	}
-	resp, err := New(LineURLProfile, http.MethodGet, p.service.ProxyURL, WithTimeout(30*time.Second), WithHeader(header)).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	value, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Println(string(value))
 	data := &LineUserProfile{}
-	err = json.Unmarshal(value, &data)
-	if err != nil {
+	if err := p.bearer(p.url(EndpointProfile, LineURLProfile), accessToken, data); err != nil {
 		return nil, err
 	}
-
 	return data, nil
 }
 
@@ -321,27 +328,23 @@ func (p *Line) FriendshipStatus(accessToken string) (bool, error) {
 	if "" == accessToken {
 		return false, ErrInvalidAccessToken
 	}
+	var data struct {
+		FriendFlag bool `json:"friendFlag"`
+	}
+	if err := p.bearer(p.url(EndpointFriendship, LineURLFriendshipStatus), accessToken, &data); err != nil {
+		return false, err
+	}
+	return data.FriendFlag, nil
+}
+
+// bearer performs a GET request authorized with the user's access token and decodes the JSON response into out.
+func (p *Line) bearer(u, accessToken string, out interface{}) error {
 	header := http.Header{
 		"Authorization": []string{fmt.Sprintf("Bearer %s", accessToken)},
 	}
-	resp, err := New(LineURLFriendshipStatus, http.MethodGet, p.service.ProxyURL,
+	return p.service.request(u, http.MethodGet,
 		WithTimeout(30*time.Second),
 		WithHeader(header),
-	).Get()
-	if err != nil {
-		return false, err
-	}
-	defer resp.Body.Close()
-	value, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return false, err
-	}
-	fmt.Println(string(value))
-	var data map[string]bool
-	err = json.Unmarshal(value, &data)
-	if err != nil {
-		return false, err
-	}
-
-	return data["friendFlag"], nil
+		WithErrorDecoder(providerErrors(AuthLine, ErrInvalidAccessToken)),
+	).DoJSON(context.Background(), out)
 }
@@ -1,18 +1,58 @@
 package oauth
 
 import (
-	"fmt"
+	"errors"
+	"net/http"
 	"testing"
+
+	"socialgoauth.org/social-goauth/oauthtest"
 )
 
 func TestLine(t *testing.T) {
-	service, err := NewService("2000596845", "d8b512a384a343465202763eeea1a0e9", AuthLine, WithProxyURL("http://127.0.0.1:8001"))
+	server := oauthtest.NewLine("2000596845", "d8b512a384a343465202763eeea1a0e9")
+	defer server.Close()
+	service, err := NewService("2000596845", "d8b512a384a343465202763eeea1a0e9", AuthLine, WithEndpoint(server.URL))
+	if nil != err {
+		t.Fatal(err)
+	}
+	line := NewLine(service)
+	accessToken := server.AccessToken()
+	information, err := line.UserInformation(accessToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if information.Sub != "000001.test" || information.Name != "Rabbit" {
+		t.Fatalf("unexpected user information %+v", information)
+	}
+	if _, err = line.UserInformation("aaaaa"); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("expected ErrInvalidAccessToken, got %v", err)
+	}
+
+	idToken, err := line.IDToken(server.IDToken("nonce", nil))
 	if nil != err {
-		panic(err)
+		t.Fatal(err)
 	}
-	line, err := NewLine(service).UserInformation("aaaaa")
+	if idToken.Nonce != "nonce" || idToken.Aud != "2000596845" {
+		t.Fatalf("unexpected ID token %+v", idToken)
+	}
+
+	refreshed, err := line.RefreshAccessToken(server.RefreshToken())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if refreshed.AccessToken == "" || refreshed.RefreshToken == "" || refreshed.ExpiresIn != 3600 {
+		t.Fatalf("unexpected refreshed token %+v", refreshed)
+	}
+
+	server.InjectError("/v2/profile", oauthtest.Error{Status: http.StatusTooManyRequests, Code: "rate_limited", Times: 1})
+	if _, err = line.UserProfile(accessToken); !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("expected ErrRateLimited only, got %v", err)
+	}
+	profile, err := line.UserProfile(accessToken)
 	if nil != err {
-		panic(err)
+		t.Fatal(err)
+	}
+	if profile.UserId != "000001.test" {
+		t.Fatalf("unexpected profile %+v", profile)
 	}
-	fmt.Println(line)
 }
@@ -0,0 +1,309 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+const (
+	MicrosoftLoginEndpoint = "https://login.microsoftonline.com"
+	MicrosoftGraphEndpoint = "https://graph.microsoft.com"
+	MicrosoftURLGraphMe    = MicrosoftGraphEndpoint + "/v1.0/me"
+
+	// MicrosoftIssuerTemplate is the issuer of the v2.0 ID tokens, {tenantid} being the tid claim.
+	// The issuer is on the login endpoint of the service, e.g. login.microsoftonline.us for US Government.
+	MicrosoftIssuerTemplate = MicrosoftLoginEndpoint + "/{tenantid}/v2.0"
+)
+
+// Authorities of the Microsoft identity platform that are not a single tenant.
+const (
+	// MicrosoftTenantCommon accepts both work or school and personal Microsoft accounts
+	MicrosoftTenantCommon = "common"
+
+	// MicrosoftTenantOrganizations accepts work or school accounts of any tenant
+	MicrosoftTenantOrganizations = "organizations"
+
+	// MicrosoftTenantConsumers accepts personal Microsoft accounts only
+	MicrosoftTenantConsumers = "consumers"
+)
+
+// MicrosoftConsumersTenantID is the tenant ID of the personal Microsoft accounts.
+const MicrosoftConsumersTenantID = "9188040d-6c67-4c5b-b112-36a304b66dad"
+
+// ErrTenantNotAllowed is returned when an ID token was issued by a tenant the provider does not accept.
+var ErrTenantNotAllowed = errors.New("tenant not allowed")
+
+var tenantIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// MicrosoftClaims struct represents the claims in a Microsoft identity platform v2.0 ID Token.
+type MicrosoftClaims struct {
+	// Expiration time of the token
+	Exp int64 `json:"exp"`
+
+	// Issued at time of the token
+	Iat int64 `json:"iat"`
+
+	// Issuer of the token, MicrosoftIssuerTemplate for the tenant of the user
+	Iss string `json:"iss"`
+
+	// Audience of the token, the client ID of the application
+	Aud string `json:"aud"`
+
+	// Subject of the token, unique to the user and the application
+	Sub string `json:"sub"`
+
+	// Tenant ID of the user, MicrosoftConsumersTenantID for personal accounts
+	Tid string `json:"tid"`
+
+	// Object ID of the user in the tenant, the same for every application
+	Oid string `json:"oid"`
+
+	// Email address of the user, if the email optional claim or scope is granted
+	Email string `json:"email"`
+
+	// Indicates if the domain of the email address is verified by the tenant
+	XmsEdov StringBool `json:"xms_edov"`
+
+	// Primary username of the user, such as an email address or a phone number, not verified
+	PreferredUsername string `json:"preferred_username"`
+
+	// Full name of the user
+	Name string `json:"name"`
+
+	// Nonce sent in the authorization request
+	Nonce string `json:"nonce"`
+
+	// Version of the token, "2.0"
+	Ver string `json:"ver"`
+
+	// Raw Claims as returned by the provider
+	Raw map[string]interface{} `json:"-"`
+}
+
+// Identity maps the claims into an Identity. The email address is reported verified for personal accounts
+// and when the tenant verified its domain.
+func (c *MicrosoftClaims) Identity() *Identity {
+	return &Identity{
+		Provider:      AuthMicrosoft,
+		Subject:       c.Sub,
+		Email:         c.Email,
+		EmailVerified: c.Email != "" && (c.Tid == MicrosoftConsumersTenantID || bool(c.XmsEdov)),
+		Name:          c.Name,
+		Raw:           c.Raw,
+	}
+}
+
+// MicrosoftUser struct represents the fields of the Microsoft Graph user returned by /me.
+type MicrosoftUser struct {
+	// ID Object ID of the user
+	ID string `json:"id"`
+
+	// DisplayName Full name of the user
+	DisplayName string `json:"displayName"`
+
+	// GivenName Given name of the user
+	GivenName string `json:"givenName"`
+
+	// Surname Family name of the user
+	Surname string `json:"surname"`
+
+	// Mail Email address of the user, may be empty for work accounts without a mailbox
+	Mail string `json:"mail"`
+
+	// UserPrincipalName Sign-in name of the user
+	UserPrincipalName string `json:"userPrincipalName"`
+
+	// Raw Fields as returned by Microsoft Graph
+	Raw map[string]interface{} `json:"-"`
+}
+
+// Microsoft struct represents the Microsoft identity platform (Entra ID) provider, for work or school and
+// personal Microsoft accounts.
+type Microsoft struct {
+	service *Service
+	tenant  string
+	keys    *jwks.Client
+
+	// AllowedTenants Tenant IDs accepted in ID tokens, every tenant of the authority when empty.
+	// Required for a tenant authority given by domain name, whose tenant ID is not known
+	AllowedTenants []string
+}
+
+// NewMicrosoft creates a new instance of the Microsoft provider for the tenant authority: MicrosoftTenantCommon
+// (the default), MicrosoftTenantOrganizations, MicrosoftTenantConsumers, or a tenant ID or domain.
+// With a domain, ID tokens are rejected unless AllowedTenants names the tenant ID.
+// URLs are derived from the Endpoint of the service, MicrosoftLoginEndpoint by default.
+func NewMicrosoft(service *Service, tenant string) *Microsoft {
+	if tenant == "" {
+		tenant = MicrosoftTenantCommon
+	}
+	if service.Endpoint == "" {
+		service.Endpoint = MicrosoftLoginEndpoint
+	}
+	keys := service.url(EndpointKeys, MicrosoftLoginEndpoint, MicrosoftLoginEndpoint+"/"+tenant+"/discovery/v2.0/keys")
+	return &Microsoft{service: service, tenant: tenant, keys: service.keys(AuthMicrosoft, keys)}
+}
+
+// Tenant returns the tenant authority of the provider.
+func (p *Microsoft) Tenant() string {
+	return p.tenant
+}
+
+// url returns the URL of the named endpoint, fallback being its default.
+func (p *Microsoft) url(name, fallback string) string {
+	return p.service.url(name, MicrosoftLoginEndpoint, fallback)
+}
+
+// authority returns the URL of the OAuth 2.0 endpoint of the tenant, e.g. "authorize" or "token".
+func (p *Microsoft) authority(endpoint string) string {
+	return MicrosoftLoginEndpoint + "/" + p.tenant + "/oauth2/v2.0/" + endpoint
+}
+
+// AuthCodeURL builds the Microsoft authorization URL, requesting the openid, profile, email and
+// offline_access scopes by default.
+//
+// documentation https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-auth-code-flow#request-an-authorization-code
+func (p *Microsoft) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	return p.service.authCodeURL(p.url(EndpointAuthorize, p.authority("authorize")), authDefaults{
+		scopes: []string{"openid", "profile", "email", "offline_access"},
+		nonce:  true,
+	}, options...)
+}
+
+// Exchange exchanges an authorization code for tokens.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+//
+// documentation https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-auth-code-flow#redeem-a-code-for-an-access-token
+func (p *Microsoft) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	return p.service.token(ctx, AuthMicrosoft, p.url(EndpointToken, p.authority("token")), AuthStylePost,
+		codeParams(code, p.service.RedirectURL, codeVerifier), ErrInvalidIdCode)
+}
+
+// Refresh gets a new access token using a refresh token. Microsoft rotates refresh tokens.
+//
+// documentation https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-auth-code-flow#refresh-the-access-token
+func (p *Microsoft) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, AuthMicrosoft, p.url(EndpointToken, p.authority("token")), AuthStylePost, refreshToken)
+}
+
+// IDToken verifies a v2.0 ID token: its signature against the keys of the Microsoft identity platform,
+// audience, expiry, the issuer of the tenant of the user, and that this tenant is accepted by the authority
+// and AllowedTenants.
+//
+// documentation https://learn.microsoft.com/en-us/entra/identity-platform/id-tokens#validate-tokens
+func (p *Microsoft) IDToken(ctx context.Context, token string) (*MicrosoftClaims, error) {
+	if token == "" {
+		return nil, ErrInvalidIdToken
+	}
+	verifier := &jwt.Verifier{
+		Algorithms: []string{jwt.RS256},
+		Keys:       keyFunc(AuthMicrosoft, p.keys),
+		Audiences:  p.service.audiences(),
+	}
+	claims := &MicrosoftClaims{}
+	parsed, err := verifier.Verify(ctx, token, claims)
+	if err != nil {
+		return nil, tokenError(AuthMicrosoft, err)
+	}
+	// Multi-tenant authorities sign for every tenant: the issuer is checked against the tenant of the token.
+	if claims.Tid == "" || claims.Iss != p.issuer(claims.Tid) {
+		return nil, &TokenError{Provider: AuthMicrosoft, Kind: ErrInvalidIdToken, Err: fmt.Errorf("%w: %q", jwt.ErrInvalidIssuer, claims.Iss)}
+	}
+	if !p.allowedTenant(claims.Tid) {
+		return nil, &TokenError{Provider: AuthMicrosoft, Kind: ErrInvalidIdToken, Err: fmt.Errorf("%w: %q", ErrTenantNotAllowed, claims.Tid)}
+	}
+	claims.Raw = rawClaims(parsed.Payload)
+	return claims, nil
+}
+
+// isTenantDomain reports whether the tenant authority is a domain name rather than a tenant ID or a
+// multi-tenant authority.
+func isTenantDomain(tenant string) bool {
+	switch strings.ToLower(tenant) {
+	case "", MicrosoftTenantCommon, MicrosoftTenantOrganizations, MicrosoftTenantConsumers:
+		return false
+	}
+	return !tenantIDPattern.MatchString(tenant)
+}
+
+// issuer returns the issuer of the ID tokens of the tenant tid, on the login endpoint of the service.
+func (p *Microsoft) issuer(tid string) string {
+	issuer := strings.Replace(MicrosoftIssuerTemplate, "{tenantid}", tid, 1)
+	if p.service.Endpoint == "" || p.service.Endpoint == MicrosoftLoginEndpoint {
+		return issuer
+	}
+	return strings.TrimSuffix(p.service.Endpoint, "/") + strings.TrimPrefix(issuer, MicrosoftLoginEndpoint)
+}
+
+// allowedTenant reports whether an ID token of the tenant tid is accepted.
+func (p *Microsoft) allowedTenant(tid string) bool {
+	if len(p.AllowedTenants) > 0 && !contains(p.AllowedTenants, tid) {
+		return false
+	}
+	switch tenant := strings.ToLower(p.tenant); {
+	case tenant == MicrosoftTenantCommon:
+		return true
+	case tenant == MicrosoftTenantOrganizations:
+		return tid != MicrosoftConsumersTenantID
+	case tenant == MicrosoftTenantConsumers:
+		return tid == MicrosoftConsumersTenantID
+	case tenantIDPattern.MatchString(tenant):
+		return strings.EqualFold(tid, tenant)
+	}
+	// A tenant given by domain name cannot be compared to tid: the keys are shared by every tenant,
+	// so the tenant must be named by AllowedTenants.
+	return len(p.AllowedTenants) > 0
+}
+
+// Me gets the profile of the user from Microsoft Graph. Requires the User.Read scope.
+// Microsoft Graph is not on the login endpoint: national clouds set its URL with WithEndpointURL(EndpointProfile, ...).
+//
+// documentation https://learn.microsoft.com/en-us/graph/api/user-get
+func (p *Microsoft) Me(ctx context.Context, accessToken string) (*MicrosoftUser, error) {
+	if accessToken == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	var raw json.RawMessage
+	me := MicrosoftURLGraphMe
+	if u := p.service.Endpoints[EndpointProfile]; u != "" {
+		me = u
+	}
+	err := p.service.request(me, http.MethodGet,
+		WithTimeout(30*time.Second),
+		WithHeader(http.Header{"Authorization": []string{"Bearer " + accessToken}}),
+		WithErrorDecoder(providerErrors(AuthMicrosoft, ErrInvalidAccessToken)),
+	).DoJSON(ctx, &raw)
+	if err != nil {
+		return nil, err
+	}
+	user := &MicrosoftUser{}
+	if err = json.Unmarshal(raw, user); err != nil {
+		return nil, err
+	}
+	user.Raw = rawClaims(raw)
+	return user, nil
+}
+
+// Identify verifies the ID token of a code exchange and returns the user it identifies.
+func (p *Microsoft) Identify(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	claims, err := p.IDToken(ctx, token.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkNonce(AuthMicrosoft, nonce, claims.Nonce); err != nil {
+		return nil, err
+	}
+	return claims.Identity(), nil
+}
@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"socialgoauth.org/social-goauth/jwt"
+	"socialgoauth.org/social-goauth/oauthtest"
+)
+
+func TestMicrosoft(t *testing.T) {
+	const tenantID = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+	server := oauthtest.NewMicrosoft("6731de76-14a6-49ae-97bc-6eba6914391e", "secret", tenantID)
+	defer server.Close()
+	server.Issuer = server.URL + "/" + tenantID + "/v2.0"
+	redirectURL := "https://example.com/callback/microsoft"
+	newMicrosoft := func(tenant string) *Microsoft {
+		service, err := NewService(server.ClientID, server.ClientSecret, AuthMicrosoft, WithRedirectURL(redirectURL),
+			WithEndpoint(server.URL), WithEndpointURL(EndpointProfile, server.URL+"/v1.0/me"))
+		if nil != err {
+			t.Fatal(err)
+		}
+		return NewMicrosoft(service, tenant)
+	}
+	ctx := context.Background()
+
+	microsoft := newMicrosoft("")
+	request, err := microsoft.AuthCodeURL()
+	if nil != err {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(request.URL)
+	if u.Path != "/common/oauth2/v2.0/authorize" || u.Query().Get("nonce") == "" {
+		t.Fatalf("unexpected authorization url %s", request.URL)
+	}
+	token, err := microsoft.Exchange(ctx, server.Code(redirectURL, request.Nonce, ""), "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	identity, err := microsoft.Identify(ctx, token, request.Nonce)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if identity.Subject != "000001.test" || identity.Email != "rabbit@example.com" || identity.EmailVerified || identity.Raw["tid"] != tenantID {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	refreshed, err := microsoft.Refresh(ctx, token.RefreshToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken == token.RefreshToken {
+		t.Fatal("expected a rotated refresh token")
+	}
+	user, err := microsoft.Me(ctx, refreshed.AccessToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if user.DisplayName != "Rabbit" || user.Mail != "rabbit@example.com" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	idToken := server.IDToken("", nil)
+	if _, err = newMicrosoft(MicrosoftTenantOrganizations).IDToken(ctx, idToken); nil != err {
+		t.Fatal(err)
+	}
+	if _, err = newMicrosoft(tenantID).IDToken(ctx, idToken); nil != err {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{MicrosoftTenantConsumers, "f8cdef31-a31e-4b4a-93e4-5f571e91255a"} {
+		if _, err = newMicrosoft(tenant).IDToken(ctx, idToken); !errors.Is(err, ErrTenantNotAllowed) || !errors.Is(err, ErrInvalidIdToken) {
+			t.Fatalf("expected ErrTenantNotAllowed for %s, got %v", tenant, err)
+		}
+	}
+	microsoft.AllowedTenants = []string{"f8cdef31-a31e-4b4a-93e4-5f571e91255a"}
+	if _, err = microsoft.IDToken(ctx, idToken); !errors.Is(err, ErrTenantNotAllowed) {
+		t.Fatalf("expected ErrTenantNotAllowed, got %v", err)
+	}
+	microsoft.AllowedTenants = nil
+
+	contoso := newMicrosoft("contoso.onmicrosoft.com")
+	if _, err = contoso.IDToken(ctx, idToken); !errors.Is(err, ErrTenantNotAllowed) {
+		t.Fatalf("expected ErrTenantNotAllowed for a domain authority without AllowedTenants, got %v", err)
+	}
+	contoso.AllowedTenants = []string{tenantID}
+	if _, err = contoso.IDToken(ctx, idToken); nil != err {
+		t.Fatal(err)
+	}
+
+	forged := server.IDToken("", map[string]interface{}{"iss": server.URL + "/f8cdef31-a31e-4b4a-93e4-5f571e91255a/v2.0"})
+	if _, err = microsoft.IDToken(ctx, forged); !errors.Is(err, jwt.ErrInvalidIssuer) {
+		t.Fatalf("expected jwt.ErrInvalidIssuer, got %v", err)
+	}
+	global := server.IDToken("", map[string]interface{}{"iss": "https://login.microsoftonline.com/" + tenantID + "/v2.0"})
+	if _, err = microsoft.IDToken(ctx, global); !errors.Is(err, jwt.ErrInvalidIssuer) {
+		t.Fatalf("expected the issuer of the global cloud to be rejected on another endpoint, got %v", err)
+	}
+	if u := NewMicrosoft(&Service{Endpoint: "https://login.microsoftonline.us"}, "").issuer(tenantID); u != "https://login.microsoftonline.us/"+tenantID+"/v2.0" {
+		t.Fatalf("unexpected issuer %s", u)
+	}
+}
+
+func TestMicrosoftConsumers(t *testing.T) {
+	server := oauthtest.NewMicrosoft("6731de76-14a6-49ae-97bc-6eba6914391e", "secret", MicrosoftConsumersTenantID)
+	defer server.Close()
+	server.Issuer = server.URL + "/" + MicrosoftConsumersTenantID + "/v2.0"
+	service, err := NewService(server.ClientID, server.ClientSecret, AuthMicrosoft, WithEndpoint(server.URL))
+	if nil != err {
+		t.Fatal(err)
+	}
+	microsoft := NewMicrosoft(service, MicrosoftTenantConsumers)
+	identity, err := microsoft.Identify(context.Background(), &Token{IdToken: server.IDToken("", nil)}, "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !identity.EmailVerified {
+		t.Fatalf("expected a verified email for a personal account %+v", identity)
+	}
+	if _, err = NewMicrosoft(service, MicrosoftTenantOrganizations).IDToken(context.Background(), server.IDToken("", nil)); !errors.Is(err, ErrTenantNotAllowed) {
+		t.Fatalf("expected ErrTenantNotAllowed, got %v", err)
+	}
+}
@@ -1,8 +1,17 @@
 package oauth
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
 )
 
 // AuthType is used to represent different types of third-party login methods.
@@ -10,10 +19,15 @@ type AuthType string
 
 // Different third-party login methods.
 const (
-	AuthGoogle   AuthType = "Google"
-	AuthApple    AuthType = "Apple"
-	AuthFacebook AuthType = "Facebook"
-	AuthLine     AuthType = "Line"
+	AuthGoogle    AuthType = "Google"
+	AuthApple     AuthType = "Apple"
+	AuthFacebook  AuthType = "Facebook"
+	AuthLine      AuthType = "Line"
+	AuthOIDC      AuthType = "OIDC"
+	AuthOAuth2    AuthType = "OAuth2"
+	AuthGitHub    AuthType = "GitHub"
+	AuthMicrosoft AuthType = "Microsoft"
+	AuthKakao     AuthType = "Kakao"
 )
 
 var (
@@ -27,6 +41,9 @@ var (
 	ErrInvalidClientID     = errors.New("invalid client id")
 	ErrInvalidClientSecret = errors.New("invalid client secret")
 	ErrInvalidRedirectURL  = errors.New("invalid redirect url")
+	ErrInvalidProxyURL     = errors.New("invalid proxy url")
+	ErrInvalidRequestURL   = errors.New("invalid request url")
+	ErrInvalidRequestBody  = errors.New("invalid request body")
 )
 
 // Service represents the basic configuration for OAuth.
@@ -37,6 +54,10 @@ type Service struct {
 	// ClientSecret Secret key used for secure communication with the third-party login provider.
 	ClientSecret string
 
+	// Audiences Additional client IDs accepted in ID tokens, e.g. of the iOS and Android apps
+	// signing in with the same provider. ClientID is always accepted and used for code exchanges.
+	Audiences []string
+
 	// RedirectURL URL that the third-party login provider redirects the user to after successful login.
 	RedirectURL string
 
@@ -47,11 +68,71 @@ type Service struct {
 	AuthType AuthType
 
 	// Endpoint where the OAuth server handles the authentication request.
+	// When it differs from the default base URL of the provider, every URL of the provider is moved onto it,
+	// keeping its path, e.g. to route through an internal gateway or a local fake.
 	Endpoint string
+
+	// Endpoints Optional URLs of single endpoints by name, e.g. EndpointToken, taking precedence over Endpoint
+	Endpoints map[string]string
+
+	// HTTPClient Optional HTTP client used by every provider request.
+	// When nil, requests share a pooled transport (honoring ProxyURL).
+	HTTPClient *http.Client
+
+	// Retry Optional policy for retrying transient failures of idempotent provider calls.
+	Retry *RetryPolicy
+
+	// secret returns the client secret of the token endpoint calls instead of ClientSecret, see WithAppleSecret
+	secret func() (string, error)
+}
+
+// Names of the provider endpoints that can be overridden with WithEndpointURL.
+const (
+	EndpointAuthorize  = "authorize"
+	EndpointToken      = "token"
+	EndpointRevoke     = "revoke"
+	EndpointKeys       = "keys"
+	EndpointUserInfo   = "userinfo"
+	EndpointVerify     = "verify"
+	EndpointProfile    = "profile"
+	EndpointFriendship = "friendship"
+	EndpointEmails     = "emails"
+	EndpointUnlink     = "unlink"
+)
+
+// endpointNames lists the names of the endpoints that can be overridden.
+var endpointNames = []string{
+	EndpointAuthorize, EndpointToken, EndpointRevoke, EndpointKeys,
+	EndpointUserInfo, EndpointVerify, EndpointProfile, EndpointFriendship, EndpointEmails, EndpointUnlink,
 }
 
 type Option func(*Service)
 
+// WithEndpoint sets the Endpoint option for the Service.
+func WithEndpoint(endpoint string) Option {
+	return func(service *Service) {
+		service.Endpoint = endpoint
+	}
+}
+
+// WithEndpointURL sets the URL of the named endpoint, e.g. EndpointToken, in the Endpoints option for the Service.
+func WithEndpointURL(name, url string) Option {
+	return func(service *Service) {
+		if service.Endpoints == nil {
+			service.Endpoints = map[string]string{}
+		}
+		service.Endpoints[name] = url
+	}
+}
+
+// WithAppleSecret sets the client secret of Sign in with Apple for the Service, renewed before it expires.
+// The clientSecret of NewService may then be empty.
+func WithAppleSecret(secret *AppleSecret) Option {
+	return func(service *Service) {
+		service.secret = secret.Secret
+	}
+}
+
 // WithRedirectURL sets the RedirectURL option for the Service.
 func WithRedirectURL(url string) Option {
 	return func(service *Service) {
@@ -59,6 +140,13 @@ func WithRedirectURL(url string) Option {
 	}
 }
 
+// WithAudiences sets the Audiences option for the Service.
+func WithAudiences(clientIDs ...string) Option {
+	return func(service *Service) {
+		service.Audiences = clientIDs
+	}
+}
+
 // WithProxyURL sets the ProxyURL option for the Service.
 func WithProxyURL(url string) Option {
 	return func(service *Service) {
@@ -66,6 +154,62 @@ func WithProxyURL(url string) Option {
 	}
 }
 
+// WithHTTPClient sets the HTTPClient option for the Service.
+func WithHTTPClient(client *http.Client) Option {
+	return func(service *Service) {
+		service.HTTPClient = client
+	}
+}
+
+// WithTransport sets the HTTPClient option for the Service to a client using the given RoundTripper.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(service *Service) {
+		service.HTTPClient = &http.Client{Transport: transport}
+	}
+}
+
+// WithRetryPolicy sets the Retry option for the Service.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(service *Service) {
+		service.Retry = policy
+	}
+}
+
+// audiences returns the client IDs accepted in ID tokens, ClientID first.
+func (s *Service) audiences() []string {
+	audiences := []string{s.ClientID}
+	for _, audience := range s.Audiences {
+		if audience != "" && !contains(audiences, audience) {
+			audiences = append(audiences, audience)
+		}
+	}
+	return audiences
+}
+
+// authorizedParty checks the azp claim of an ID token: when present, it must be one of the accepted client IDs.
+func (s *Service) authorizedParty(provider AuthType, azp string) error {
+	if azp != "" && !contains(s.audiences(), azp) {
+		return &TokenError{Provider: provider, Kind: ErrInvalidIdToken, Err: fmt.Errorf("unexpected azp %q", azp)}
+	}
+	return nil
+}
+
+// url returns the URL of the named endpoint of a provider whose default URL is fallback: the URL set in
+// Endpoints, else fallback moved onto Endpoint when Endpoint is not the default base of the provider.
+func (s *Service) url(name, base, fallback string) string {
+	if u := s.Endpoints[name]; u != "" {
+		return u
+	}
+	if fallback == "" || s.Endpoint == "" || s.Endpoint == base {
+		return fallback
+	}
+	u, err := url.Parse(fallback)
+	if err != nil {
+		return fallback
+	}
+	return Endpoint(strings.TrimSuffix(s.Endpoint, "/"), u.RequestURI())
+}
+
 // Endpoint returns a URL endpoint given an input string and an endpoint base.
 // If the input string begins with "http://" or "https://", it is returned as-is.
 // If the input string begins with "/", it is appended to the endpoint base.
@@ -88,13 +232,97 @@ func NewService(clientID, clientSecret string, authType AuthType, options ...Opt
 	if clientID == "" {
 		return nil, ErrInvalidClientID
 	}
-	if clientSecret == "" {
-		return nil, ErrInvalidClientSecret
-	}
 	service := &Service{ClientID: clientID, ClientSecret: clientSecret, AuthType: authType}
 	for _, opt := range options {
 		opt(service)
 	}
+	if clientSecret == "" && service.secret == nil {
+		return nil, ErrInvalidClientSecret
+	}
+	if service.ProxyURL != "" {
+		if _, err := parseProxyURL(service.ProxyURL); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProxyURL, err)
+		}
+	}
+	if service.Endpoint != "" {
+		if u, err := url.Parse(service.Endpoint); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRequestURL, service.Endpoint)
+		}
+	}
+	if service.RedirectURL != "" {
+		if err := validateRedirectURL(service.RedirectURL); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRedirectURL, err)
+		}
+	}
 
 	return service, nil
 }
+
+// parseProxyURL parses a proxy URL, accepting only http, https and socks5 proxies with a host.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing proxy host")
+	}
+	return u, nil
+}
+
+// validateRedirectURL checks that a redirect URL is absolute.
+// Custom schemes used by native apps are allowed; http and https URLs must carry a host.
+func validateRedirectURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		return errors.New("redirect url must be absolute")
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return errors.New("missing redirect host")
+	}
+	if u.Fragment != "" {
+		return errors.New("redirect url must not contain a fragment")
+	}
+	return nil
+}
+
+// request creates a new Request for the service, carrying over its proxy and HTTP client.
+func (s *Service) request(url, method string, options ...ROption) *Request {
+	options = append([]ROption{WithClient(s.HTTPClient), WithRetry(s.Retry)}, options...)
+	return New(url, method, s.ProxyURL, options...)
+}
+
+// keys creates a JWKS client for the provider key set at url, fetched through the service's requests.
+func (s *Service) keys(provider AuthType, url string) *jwks.Client {
+	return jwks.NewClient(url, jwks.WithFetcher(func(ctx context.Context, url string) ([]byte, error) {
+		var document json.RawMessage
+		err := s.request(url, http.MethodGet,
+			WithTimeout(30*time.Second),
+			WithErrorDecoder(providerErrors(provider, ErrFetchKeysFail)),
+		).DoJSON(ctx, &document)
+		return document, err
+	}))
+}
+
+// keyFunc resolves token verification keys from a provider key set.
+// An unknown key ID is reported as an invalid signature.
+func keyFunc(provider AuthType, keys *jwks.Client) jwt.KeyFunc {
+	return func(ctx context.Context, header jwt.Header) (interface{}, error) {
+		key, err := keys.Key(ctx, header.Kid, header.Alg)
+		if errors.Is(err, jwks.ErrKeyNotFound) {
+			return nil, &TokenError{Provider: provider, Kind: ErrInvalidSignature, Err: err}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return key.Public, nil
+	}
+}
@@ -0,0 +1,233 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OAuth2UserMapping maps userinfo JSON paths to the normalized Identity fields.
+//
+// Paths are dot separated object keys, with numeric segments indexing arrays,
+// e.g. "data.id" or "emails.0.value". Empty paths use the defaults of DefaultOAuth2UserMapping.
+type OAuth2UserMapping struct {
+	Subject       string `json:"subject" yaml:"subject"`
+	Email         string `json:"email" yaml:"email"`
+	EmailVerified string `json:"email_verified" yaml:"email_verified"`
+	Name          string `json:"name" yaml:"name"`
+	Picture       string `json:"picture" yaml:"picture"`
+}
+
+// DefaultOAuth2UserMapping is used for the fields left empty in an OAuth2UserMapping.
+var DefaultOAuth2UserMapping = OAuth2UserMapping{
+	Subject:       "id",
+	Email:         "email",
+	EmailVerified: "email_verified",
+	Name:          "name",
+	Picture:       "picture",
+}
+
+// OAuth2Config describes a plain OAuth 2.0 provider without OpenID Connect support.
+type OAuth2Config struct {
+	// AuthURL Authorization endpoint the user is redirected to
+	AuthURL string `json:"auth_url" yaml:"auth_url"`
+
+	// TokenURL Token endpoint used for code exchange and refresh
+	TokenURL string `json:"token_url" yaml:"token_url"`
+
+	// UserInfoURL Endpoint returning the profile of the user
+	UserInfoURL string `json:"userinfo_url" yaml:"userinfo_url"`
+
+	// RevokeURL Optional RFC 7009 revocation endpoint
+	RevokeURL string `json:"revoke_url" yaml:"revoke_url"`
+
+	// Scopes requested by default
+	Scopes []string `json:"scopes" yaml:"scopes"`
+
+	// AuthStyle How the client credentials are sent to the token endpoint
+	AuthStyle AuthStyle `json:"auth_style" yaml:"auth_style"`
+
+	// UserMapping Mapping from the userinfo response to the Identity fields
+	UserMapping OAuth2UserMapping `json:"user_mapping" yaml:"user_mapping"`
+}
+
+// OAuth2 struct represents a generic OAuth 2.0 provider configured with OAuth2Config.
+type OAuth2 struct {
+	service *Service
+	config  OAuth2Config
+	base    string
+}
+
+// NewOAuth2 creates a new instance of a generic OAuth 2.0 provider.
+func NewOAuth2(service *Service, config OAuth2Config) (*OAuth2, error) {
+	if config.TokenURL == "" {
+		return nil, fmt.Errorf("%w: missing token url", ErrInvalidRequestURL)
+	}
+	mapping := &config.UserMapping
+	defaults := DefaultOAuth2UserMapping
+	for _, field := range []struct{ value, fallback *string }{
+		{&mapping.Subject, &defaults.Subject},
+		{&mapping.Email, &defaults.Email},
+		{&mapping.EmailVerified, &defaults.EmailVerified},
+		{&mapping.Name, &defaults.Name},
+		{&mapping.Picture, &defaults.Picture},
+	} {
+		if *field.value == "" {
+			*field.value = *field.fallback
+		}
+	}
+	var base string
+	if u, err := url.Parse(config.AuthURL); err == nil && u.Host != "" {
+		base = u.Scheme + "://" + u.Host
+	}
+	if service.Endpoint == "" {
+		service.Endpoint = base
+	}
+	return &OAuth2{service: service, config: config, base: base}, nil
+}
+
+// url returns the URL of the named endpoint, fallback being the configured one.
+func (p *OAuth2) url(name, fallback string) string {
+	return p.service.url(name, p.base, fallback)
+}
+
+// Config returns the provider configuration.
+func (p *OAuth2) Config() OAuth2Config {
+	return p.config
+}
+
+// AuthCodeURL builds the authorization URL, requesting the configured scopes by default.
+func (p *OAuth2) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	endpoint := p.url(EndpointAuthorize, p.config.AuthURL)
+	if endpoint == "" {
+		return nil, ErrNotSupported
+	}
+	return p.service.authCodeURL(endpoint, authDefaults{scopes: p.config.Scopes}, options...)
+}
+
+// Exchange exchanges an authorization code for tokens at the token endpoint.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+func (p *OAuth2) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	params := codeParams(code, p.service.RedirectURL, codeVerifier)
+	return p.service.token(ctx, p.service.AuthType, p.url(EndpointToken, p.config.TokenURL), p.config.AuthStyle, params, ErrInvalidIdCode)
+}
+
+// Refresh gets a new access token using a refresh token.
+func (p *OAuth2) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, p.service.AuthType, p.url(EndpointToken, p.config.TokenURL), p.config.AuthStyle, refreshToken)
+}
+
+// UserInfo fetches the user profile and maps it into an Identity.
+func (p *OAuth2) UserInfo(ctx context.Context, accessToken string) (*Identity, error) {
+	if accessToken == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	endpoint := p.url(EndpointUserInfo, p.config.UserInfoURL)
+	if endpoint == "" {
+		return nil, ErrNotSupported
+	}
+	var document json.RawMessage
+	err := p.service.request(endpoint, http.MethodGet,
+		WithTimeout(30*time.Second),
+		WithHeader(http.Header{
+			"Authorization": []string{"Bearer " + accessToken},
+			"Accept":        []string{"application/json"},
+		}),
+		WithErrorDecoder(providerErrors(p.service.AuthType, ErrInvalidAccessToken)),
+	).DoJSON(ctx, &document)
+	if err != nil {
+		return nil, err
+	}
+	return p.config.UserMapping.identity(p.service.AuthType, document)
+}
+
+// Identify returns the user of a code exchange from the userinfo endpoint.
+// OAuth 2.0 has no ID token, the nonce is not used.
+func (p *OAuth2) Identify(ctx context.Context, token *Token, _ string) (*Identity, error) {
+	return p.UserInfo(ctx, token.AccessToken)
+}
+
+// Revoke invalidates an access or refresh token at the revocation endpoint (RFC 7009).
+func (p *OAuth2) Revoke(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidAccessToken
+	}
+	endpoint := p.url(EndpointRevoke, p.config.RevokeURL)
+	if endpoint == "" {
+		return ErrNotSupported
+	}
+	params := url.Values{"token": []string{token}, "client_id": []string{p.service.ClientID}}
+	if p.service.ClientSecret != "" {
+		params.Set("client_secret", p.service.ClientSecret)
+	}
+	return p.service.request(endpoint, http.MethodPost,
+		WithTimeout(30*time.Second),
+		WithContentType(ContentTypeWWWForm),
+		WithData(params),
+		WithErrorDecoder(providerErrors(p.service.AuthType, ErrInvalidAccessToken)),
+	).DoJSON(ctx, nil)
+}
+
+// identity maps a userinfo document into an Identity.
+func (m OAuth2UserMapping) identity(provider AuthType, document []byte) (*Identity, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Provider: provider,
+		Subject:  lookupString(value, m.Subject),
+		Email:    lookupString(value, m.Email),
+		Name:     lookupString(value, m.Name),
+		Picture:  lookupString(value, m.Picture),
+	}
+	identity.EmailVerified, _ = strconv.ParseBool(lookupString(value, m.EmailVerified))
+	if raw, ok := value.(map[string]interface{}); ok {
+		identity.Raw = raw
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject at %q", ErrInvalidAccessToken, m.Subject)
+	}
+	return identity, nil
+}
+
+// lookupString resolves a dot separated path in a decoded JSON value and formats the result as a string.
+func lookupString(value interface{}, path string) string {
+	if path == "" {
+		return ""
+	}
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return ""
+			}
+			value = v[index]
+		default:
+			return ""
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuth2(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok || r.PostFormValue("client_secret") != "secret" || r.PostFormValue("code") != "good" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","token_type":"bearer","expires_in":7200}`))
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"id":9007199254740993,"profile":{"nick":"rabbit"},"emails":[{"value":"rabbit@example.com","verified":true}]}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var config OAuth2Config
+	err := json.Unmarshal([]byte(`{
+		"auth_url": "`+server.URL+`/authorize",
+		"token_url": "`+server.URL+`/token",
+		"userinfo_url": "`+server.URL+`/me",
+		"auth_style": "post",
+		"user_mapping": {"subject": "data.id", "name": "data.profile.nick", "email": "data.emails.0.value", "email_verified": "data.emails.0.verified"}
+	}`), &config)
+	if nil != err {
+		t.Fatal(err)
+	}
+	service, err := NewService("client", "secret", "Custom")
+	if nil != err {
+		t.Fatal(err)
+	}
+	provider, err := NewOAuth2(service, config)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	token, err := provider.Exchange(ctx, "good", "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	identity, err := provider.UserInfo(ctx, token.AccessToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if identity.Provider != "Custom" || identity.Subject != "9007199254740993" || identity.Name != "rabbit" ||
+		identity.Email != "rabbit@example.com" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+}
@@ -0,0 +1,36 @@
+package oauth
+
+import "testing"
+
+func TestServiceURL(t *testing.T) {
+	service, err := NewService("client", "secret", AuthLine)
+	if nil != err {
+		t.Fatal(err)
+	}
+	line := NewLine(service)
+	if u := line.url(EndpointProfile, LineURLProfile); u != LineURLProfile {
+		t.Fatalf("unexpected default url %s", u)
+	}
+
+	service, err = NewService("client", "secret", AuthLine,
+		WithEndpoint("https://gateway.example.com/line/"),
+		WithEndpointURL(EndpointToken, "https://token.example.com/token"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	line = NewLine(service)
+	for _, c := range []struct{ name, fallback, expected string }{
+		{EndpointProfile, LineURLProfile, "https://gateway.example.com/line/v2/profile"},
+		{EndpointAuthorize, LineURLAuthorize, "https://gateway.example.com/line/oauth2/v2.1/authorize"},
+		{EndpointToken, LineURLAccessToken, "https://token.example.com/token"},
+		{EndpointRevoke, "", ""},
+	} {
+		if u := line.url(c.name, c.fallback); u != c.expected {
+			t.Fatalf("expected %s for %s, got %s", c.expected, c.name, u)
+		}
+	}
+
+	if _, err = NewService("client", "secret", AuthLine, WithEndpoint("gateway")); err == nil {
+		t.Fatal("expected an error for an endpoint without host")
+	}
+}
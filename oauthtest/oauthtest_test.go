@@ -0,0 +1,75 @@
+package oauthtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	oauth "socialgoauth.org/social-goauth"
+	"socialgoauth.org/social-goauth/oauthtest"
+)
+
+func TestGoogleLogin(t *testing.T) {
+	server := oauthtest.NewGoogle("web.apps.googleusercontent.com", "secret")
+	defer server.Close()
+	service, err := oauth.NewService("web.apps.googleusercontent.com", "secret", oauth.AuthGoogle,
+		oauth.WithRedirectURL("https://example.com/callback/google"), oauth.WithHTTPClient(server.Client()))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var identity *oauth.Identity
+	handler := oauth.NewHandler("google", oauth.NewGoogle(service), oauth.NewMemoryStateStore(0, 0),
+		oauth.WithOnSuccess(func(w http.ResponseWriter, r *http.Request, id *oauth.Identity, token *oauth.Token) {
+			identity = id
+		}),
+		oauth.WithOnError(func(w http.ResponseWriter, r *http.Request, err error) {
+			t.Fatalf("unexpected login error %v", err)
+		}),
+	)
+
+	// Follow the redirects of the login through the fake authorization endpoint.
+	recorder := httptest.NewRecorder()
+	handler.Login(recorder, httptest.NewRequest(http.MethodGet, "/login/google", nil))
+	resp, err := (&http.Client{
+		Transport:     server.Transport(),
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}).Get(recorder.Header().Get("Location"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	handler.Callback(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil))
+	if identity == nil || identity.Subject != "000001.test" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+}
+
+func TestFacebookErrors(t *testing.T) {
+	server := oauthtest.NewFacebook("app", "secret")
+	defer server.Close()
+	service, _ := oauth.NewService("app", "secret", oauth.AuthFacebook,
+		oauth.WithRedirectURL("https://example.com/callback/facebook"), oauth.WithTransport(server.Transport()))
+	facebook := oauth.NewFacebook(service)
+	ctx := context.Background()
+
+	token, err := facebook.Exchange(ctx, server.Code("https://example.com/callback/facebook", "", ""), "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	server.InjectError("/v18.0/me", oauthtest.Error{Status: http.StatusBadRequest, Code: "OAuthException", Description: "Application request limit reached", ErrorCode: 4, Times: 1})
+	if _, err = facebook.Identify(ctx, token, ""); !errors.Is(err, oauth.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	identity, err := facebook.Identify(ctx, token, "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if identity.Subject != "000001.test" || identity.Picture != "https://example.com/rabbit.png" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+}
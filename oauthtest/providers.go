@@ -0,0 +1,303 @@
+package oauthtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+// NewApple starts a fake Sign in with Apple server.
+//
+// Routes: /auth/authorize, /auth/token, /auth/revoke and /auth/keys.
+func NewApple(clientID, clientSecret string) *Server {
+	s := newServer("Apple", "https://appleid.apple.com", clientID, clientSecret)
+	s.mux.HandleFunc("/auth/authorize", s.authorize)
+	s.mux.HandleFunc("/auth/token", s.token)
+	s.mux.HandleFunc("/auth/revoke", s.revoke("token"))
+	s.mux.HandleFunc("/auth/keys", s.keys)
+	return s
+}
+
+// NewGoogle starts a fake Google server.
+//
+// Routes: /o/oauth2/v2/auth, /token, /revoke, /oauth2/v3/certs and /oauth2/v3/userinfo.
+func NewGoogle(clientID, clientSecret string) *Server {
+	s := newServer("Google", "https://accounts.google.com", clientID, clientSecret)
+	s.mux.HandleFunc("/o/oauth2/v2/auth", s.authorize)
+	s.mux.HandleFunc("/token", s.token)
+	s.mux.HandleFunc("/revoke", s.revoke("token"))
+	s.mux.HandleFunc("/oauth2/v3/certs", s.keys)
+	s.mux.HandleFunc("/oauth2/v3/userinfo", s.userinfo(func(user User) interface{} {
+		return map[string]interface{}{
+			"sub":            user.Subject,
+			"email":          user.Email,
+			"email_verified": user.EmailVerified,
+			"name":           user.Name,
+			"picture":        user.Picture,
+		}
+	}))
+	return s
+}
+
+// NewFacebook starts a fake Facebook server. Facebook issues no refresh or ID tokens.
+//
+// Routes, for any Graph API version: /{version}/dialog/oauth, /{version}/oauth/access_token and /{version}/me,
+// and the JWKS of the Limited Login tokens signed by IDToken at /.well-known/oauth/openid/jwks/.
+func NewFacebook(clientID, clientSecret string) *Server {
+	s := newServer("Facebook", "https://www.facebook.com", clientID, clientSecret)
+	me := s.userinfo(func(user User) interface{} {
+		return map[string]interface{}{
+			"id":      user.Subject,
+			"name":    user.Name,
+			"email":   user.Email,
+			"picture": map[string]interface{}{"data": map[string]interface{}{"url": user.Picture}},
+		}
+	})
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dialog/oauth"):
+			s.authorize(w, r)
+		case strings.HasSuffix(r.URL.Path, "/oauth/access_token"):
+			s.token(w, r)
+		case strings.HasSuffix(r.URL.Path, "/me"):
+			me(w, r)
+		case r.URL.Path == "/.well-known/oauth/openid/jwks/":
+			s.keys(w, r)
+		default:
+			s.writeError(w, http.StatusNotFound, "GraphMethodException", "unknown path", 100)
+		}
+	})
+	return s
+}
+
+// NewLine starts a fake LINE Login server. Refresh tokens are rotated on every refresh.
+//
+// Routes: /oauth2/v2.1/authorize, /oauth2/v2.1/token, /oauth2/v2.1/verify, /oauth2/v2.1/revoke,
+// /oauth2/v2.1/userinfo, /v2/profile and /friendship/v1/status.
+func NewLine(clientID, clientSecret string) *Server {
+	s := newServer("Line", "https://access.line.me", clientID, clientSecret)
+	s.rotateRefresh = true
+	s.mux.HandleFunc("/oauth2/v2.1/authorize", s.authorize)
+	s.mux.HandleFunc("/oauth2/v2.1/token", s.token)
+	s.mux.HandleFunc("/oauth2/v2.1/verify", s.lineVerify)
+	s.mux.HandleFunc("/oauth2/v2.1/revoke", s.revoke("access_token"))
+	s.mux.HandleFunc("/oauth2/v2.1/userinfo", s.userinfo(func(user User) interface{} {
+		return map[string]interface{}{"sub": user.Subject, "name": user.Name, "picture": user.Picture}
+	}))
+	s.mux.HandleFunc("/v2/profile", s.userinfo(func(user User) interface{} {
+		return map[string]interface{}{"userId": user.Subject, "displayName": user.Name, "pictureUrl": user.Picture}
+	}))
+	s.mux.HandleFunc("/friendship/v1/status", s.userinfo(func(User) interface{} {
+		return map[string]interface{}{"friendFlag": true}
+	}))
+	return s
+}
+
+// lineVerify implements the LINE verify endpoint: GET verifies an access token, POST an ID token.
+func (s *Server) lineVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		access := s.bearer(r)
+		if access == nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "access token expired", 0)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"scope":      "profile openid email",
+			"client_id":  access.clientID,
+			"expires_in": int(time.Until(access.expiry) / time.Second),
+		})
+		return
+	}
+
+	idToken := r.PostFormValue("id_token")
+	s.mu.Lock()
+	issued := s.idTokens[idToken]
+	s.mu.Unlock()
+	parsed, err := jwt.Parse(idToken)
+	if issued == nil || err != nil || issued.clientID != r.PostFormValue("client_id") {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid IdToken.", 0)
+		return
+	}
+	if nonce := r.PostFormValue("nonce"); nonce != "" && nonce != issued.nonce {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid IdToken Nonce.", 0)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(parsed.Payload)
+}
+
+// NewGitHub starts a fake GitHub server laid out as a GitHub Enterprise Server, to be used as the Endpoint
+// of the service. GitHub issues no refresh or ID tokens, and reports token errors with a 200 status.
+// The default user has the numeric Subject GitHub uses.
+//
+// Routes: /login/oauth/authorize, /login/oauth/access_token, /api/v3/user, /api/v3/user/emails and
+// DELETE /api/v3/applications/{client_id}/token. The API routes are also served without the /api/v3 prefix,
+// as on api.github.com.
+func NewGitHub(clientID, clientSecret string) *Server {
+	s := newServer("GitHub", "https://github.com", clientID, clientSecret)
+	s.user.Subject = "583231"
+	s.mux.HandleFunc("/login/oauth/authorize", s.authorize)
+	s.mux.HandleFunc("/login/oauth/access_token", s.token)
+	for _, prefix := range []string{"", "/api/v3"} {
+		s.mux.HandleFunc(prefix+"/user", s.userinfo(func(user User) interface{} {
+			id, _ := strconv.ParseInt(user.Subject, 10, 64)
+			return map[string]interface{}{
+				"id":         id,
+				"login":      strings.ToLower(user.Name),
+				"name":       user.Name,
+				"email":      nil,
+				"avatar_url": user.Picture,
+			}
+		}))
+		s.mux.HandleFunc(prefix+"/user/emails", s.userinfo(func(user User) interface{} {
+			return []map[string]interface{}{
+				{"email": user.Subject + "+" + strings.ToLower(user.Name) + "@users.noreply.github.com", "primary": false, "verified": true, "visibility": nil},
+				{"email": user.Email, "primary": true, "verified": user.EmailVerified, "visibility": "private"},
+			}
+		}))
+		s.mux.HandleFunc(prefix+"/applications/"+clientID+"/token", s.githubRevoke)
+	}
+	return s
+}
+
+// githubRevoke implements the GitHub endpoint deleting an app token, authenticated with the client credentials.
+func (s *Server) githubRevoke(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if r.Method != http.MethodDelete || !ok || clientID != s.ClientID || clientSecret != s.ClientSecret {
+		s.writeError(w, http.StatusNotFound, "", "Not Found", 0)
+		return
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.writeError(w, http.StatusUnprocessableEntity, "", "Invalid request", 0)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessTokens[body.AccessToken] == nil {
+		s.writeError(w, http.StatusNotFound, "", "Not Found", 0)
+		return
+	}
+	delete(s.accessTokens, body.AccessToken)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// NewMicrosoft starts a fake Microsoft identity platform whose users belong to the tenant tenantID,
+// e.g. oauth.MicrosoftConsumersTenantID for personal accounts. ID tokens carry the tid claim and
+// the issuer of the tenant.
+//
+// Routes, for any tenant authority: /{tenant}/oauth2/v2.0/authorize, /{tenant}/oauth2/v2.0/token,
+// /{tenant}/discovery/v2.0/keys, and the Microsoft Graph /v1.0/me.
+//
+// Used as the Endpoint of the service, the issuer is expected on the server: set Issuer to URL + "/{tenantid}/v2.0",
+// and the URL of /v1.0/me with the profile endpoint override, Microsoft Graph having its own host.
+func NewMicrosoft(clientID, clientSecret, tenantID string) *Server {
+	s := newServer("Microsoft", "https://login.microsoftonline.com/"+tenantID+"/v2.0", clientID, clientSecret)
+	s.tenantID = tenantID
+	s.rotateRefresh = true
+	me := s.userinfo(func(user User) interface{} {
+		return map[string]interface{}{
+			"id":                "00000000-0000-0000-" + user.Subject,
+			"displayName":       user.Name,
+			"mail":              user.Email,
+			"userPrincipalName": user.Email,
+		}
+	})
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1.0/me":
+			me(w, r)
+		case strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/authorize"):
+			s.authorize(w, r)
+		case strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token"):
+			s.token(w, r)
+		case strings.HasSuffix(r.URL.Path, "/discovery/v2.0/keys"):
+			s.keys(w, r)
+		default:
+			s.writeError(w, http.StatusNotFound, "invalid_request", "unknown path", 0)
+		}
+	})
+	return s
+}
+
+// KakaoAppID is the application ID reported by the token info endpoint of a fake Kakao server.
+const KakaoAppID = 1053742
+
+// NewKakao starts a fake Kakao server serving both kauth.kakao.com and kapi.kakao.com, to be used with its Transport:
+// the kapi.kakao.com URLs do not follow the Endpoint of the service. The default user has a numeric Subject like Kakao user IDs.
+//
+// Routes: /oauth/authorize, /oauth/token, /.well-known/jwks.json, /v2/user/me, /v1/user/access_token_info,
+// POST /v1/user/logout and POST /v1/user/unlink.
+func NewKakao(clientID, clientSecret string) *Server {
+	s := newServer("Kakao", "https://kauth.kakao.com", clientID, clientSecret)
+	s.user.Subject = "3141592653"
+	s.mux.HandleFunc("/oauth/authorize", s.authorize)
+	s.mux.HandleFunc("/oauth/token", s.token)
+	s.mux.HandleFunc("/.well-known/jwks.json", s.keys)
+	s.mux.HandleFunc("/v2/user/me", s.userinfo(func(user User) interface{} {
+		id, _ := strconv.ParseInt(user.Subject, 10, 64)
+		return map[string]interface{}{
+			"id":           id,
+			"connected_at": "2024-01-02T03:04:05Z",
+			"kakao_account": map[string]interface{}{
+				"profile_nickname_needs_agreement": false,
+				"profile": map[string]interface{}{
+					"nickname":            user.Name,
+					"profile_image_url":   user.Picture,
+					"thumbnail_image_url": user.Picture,
+				},
+				"email_needs_agreement": false,
+				"has_email":             user.Email != "",
+				"is_email_valid":        user.Email != "",
+				"is_email_verified":     user.EmailVerified,
+				"email":                 user.Email,
+			},
+		}
+	}))
+	s.mux.HandleFunc("/v1/user/access_token_info", func(w http.ResponseWriter, r *http.Request) {
+		access := s.bearer(r)
+		if access == nil {
+			s.writeError(w, http.StatusUnauthorized, "invalid_token", "this access token does not exist", 0)
+			return
+		}
+		id, _ := strconv.ParseInt(access.user.Subject, 10, 64)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":         id,
+			"expires_in": int(time.Until(access.expiry) / time.Second),
+			"app_id":     KakaoAppID,
+		})
+	})
+	s.mux.HandleFunc("/v1/user/logout", s.kakaoExpire)
+	s.mux.HandleFunc("/v1/user/unlink", s.kakaoExpire)
+	return s
+}
+
+// kakaoExpire implements the Kakao logout and unlink endpoints, expiring every token of the user.
+func (s *Server) kakaoExpire(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "invalid_request", "expected POST", -2)
+		return
+	}
+	access := s.bearer(r)
+	if access == nil {
+		s.writeError(w, http.StatusUnauthorized, "invalid_token", "this access token does not exist", 0)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tokens := range []map[string]*grant{s.accessTokens, s.refreshTokens} {
+		for token, issued := range tokens {
+			if issued.user.Subject == access.user.Subject {
+				delete(tokens, token)
+			}
+		}
+	}
+	id, _ := strconv.ParseInt(access.user.Subject, 10, 64)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+}
@@ -0,0 +1,547 @@
+// Package oauthtest provides fake Apple, Google, Facebook, LINE, GitHub, Microsoft and Kakao servers for hermetic tests.
+//
+// Each Server issues authorization codes, access, refresh and ID tokens signed with a generated RSA key,
+// serves its JWKS, and implements the token, revoke and user endpoints of the provider with the same paths
+// as the real one. Requests to the real hosts are routed to a Server with its Transport or Client:
+//
+//	apple := oauthtest.NewApple("com.example.web", "secret")
+//	defer apple.Close()
+//	service, _ := oauth.NewService("com.example.web", "secret", oauth.AuthApple, oauth.WithTransport(apple.Transport()))
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+// TokenLifetime is the lifetime of the access and ID tokens issued by a Server.
+const TokenLifetime = time.Hour
+
+// User is the user authenticated by a Server.
+type User struct {
+	// Subject Unique identifier of the user
+	Subject string
+
+	// Email address of the user
+	Email string
+
+	// EmailVerified Indicates if the email address is verified
+	EmailVerified bool
+
+	// Name Display name of the user
+	Name string
+
+	// Picture Profile picture URL
+	Picture string
+}
+
+// Error is an error returned by a Server instead of the normal response, see InjectError.
+type Error struct {
+	// Status HTTP status code of the response
+	Status int
+
+	// Code OAuth error code, e.g. "invalid_grant", or the Graph API error type for Facebook
+	Code string
+
+	// Description Human-readable error description
+	Description string
+
+	// ErrorCode Numeric error code of the Facebook Graph API or the Kakao API
+	ErrorCode int
+
+	// RetryAfter Value of the Retry-After header, if any
+	RetryAfter time.Duration
+
+	// Times Number of requests failing with the error, every request when zero
+	Times int
+}
+
+// grant is what an authorization code or a token was issued for.
+type grant struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	scope       string
+	user        User
+	expiry      time.Time
+}
+
+// Server is a fake provider. The zero value is not usable, create it with NewApple, NewGoogle,
+// NewFacebook, NewLine, NewGitHub, NewMicrosoft or NewKakao.
+type Server struct {
+	*httptest.Server
+
+	// ClientID Client ID accepted by the token endpoint and used as the ID token audience
+	ClientID string
+
+	// ClientSecret Client secret accepted by the token endpoint
+	ClientSecret string
+
+	// Issuer Issuer of the ID tokens, the one of the real provider
+	Issuer string
+
+	provider      string
+	tenantID      string
+	key           *rsa.PrivateKey
+	kid           string
+	rotateRefresh bool
+	mux           *http.ServeMux
+
+	mu            sync.Mutex
+	user          User
+	codes         map[string]*grant
+	accessTokens  map[string]*grant
+	refreshTokens map[string]*grant
+	idTokens      map[string]*grant
+	failures      map[string]*Error
+	requests      map[string]int
+}
+
+// newServer creates a Server for the provider, whose routes are added by the provider constructors.
+func newServer(provider, issuer, clientID, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oauthtest: " + err.Error())
+	}
+	s := &Server{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		Issuer:        issuer,
+		provider:      provider,
+		key:           key,
+		kid:           strings.ToLower(provider) + "-test-key",
+		mux:           http.NewServeMux(),
+		user:          User{Subject: "000001.test", Email: "rabbit@example.com", EmailVerified: true, Name: "Rabbit", Picture: "https://example.com/rabbit.png"},
+		codes:         map[string]*grant{},
+		accessTokens:  map[string]*grant{},
+		refreshTokens: map[string]*grant{},
+		idTokens:      map[string]*grant{},
+		failures:      map[string]*Error{},
+		requests:      map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SetUser sets the user authenticated by the next authorization codes.
+func (s *Server) SetUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// InjectError makes the requests to path fail with the error, e.g. "/auth/token".
+// An error with a zero Status removes the injected error.
+func (s *Server) InjectError(path string, e Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.Status == 0 {
+		delete(s.failures, path)
+		return
+	}
+	s.failures[path] = &e
+}
+
+// Requests returns the number of requests received on path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// Endpoint returns the base URL of the Server.
+func (s *Server) Endpoint() string {
+	return s.URL
+}
+
+// Transport returns a RoundTripper sending every request to the Server, whatever its host.
+func (s *Server) Transport() http.RoundTripper {
+	return &rewriteTransport{target: s.URL}
+}
+
+// Client returns an HTTP client sending every request to the Server, whatever its host.
+func (s *Server) Client() *http.Client {
+	return &http.Client{Transport: s.Transport()}
+}
+
+// PublicKey returns the public key of the ID token signing key.
+func (s *Server) PublicKey() *rsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+// Code issues an authorization code for the current user, as the authorization endpoint does.
+// challenge is the S256 PKCE code challenge, empty without PKCE.
+func (s *Server) Code(redirectURI, nonce, challenge string) string {
+	return s.code(redirectURI, nonce, challenge, "")
+}
+
+// code issues an authorization code granting scope, returned by the token endpoint when not empty.
+func (s *Server) code(redirectURI, nonce, challenge, scope string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := randomString()
+	s.codes[code] = &grant{
+		clientID:    s.ClientID,
+		redirectURI: redirectURI,
+		nonce:       nonce,
+		challenge:   challenge,
+		scope:       scope,
+		user:        s.user,
+		expiry:      time.Now().Add(10 * time.Minute),
+	}
+	return code
+}
+
+// IDToken signs an ID token for the current user, with extra claims overriding the default ones.
+func (s *Server) IDToken(nonce string, extra map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issued := &grant{clientID: s.ClientID, nonce: nonce, user: s.user}
+	token := s.signIDToken(issued, extra)
+	s.idTokens[token] = issued
+	return token
+}
+
+// AccessToken issues an access token for the current user, accepted by the user endpoints.
+func (s *Server) AccessToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := randomString()
+	s.accessTokens[token] = &grant{clientID: s.ClientID, user: s.user, expiry: time.Now().Add(TokenLifetime)}
+	return token
+}
+
+// RefreshToken issues a refresh token for the current user.
+func (s *Server) RefreshToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := randomString()
+	s.refreshTokens[token] = &grant{clientID: s.ClientID, user: s.user}
+	return token
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	failure := s.failures[r.URL.Path]
+	if failure != nil && failure.Times > 0 {
+		if failure.Times--; failure.Times == 0 {
+			delete(s.failures, r.URL.Path)
+		}
+	}
+	s.mu.Unlock()
+
+	if failure != nil {
+		if failure.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(failure.RetryAfter/time.Second)))
+		}
+		s.writeError(w, failure.Status, failure.Code, failure.Description, failure.ErrorCode)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorize implements an authorization endpoint that consents immediately:
+// the user is redirected to redirect_uri with a code and the state.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if query.Get("client_id") != s.ClientID || redirectURI == "" || err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "unknown client or redirect_uri", 0)
+		return
+	}
+	if method := query.Get("code_challenge_method"); method != "" && method != "S256" {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "unsupported code_challenge_method", 0)
+		return
+	}
+	code := s.code(redirectURI, query.Get("nonce"), query.Get("code_challenge"), query.Get("scope"))
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token implements the authorization_code and refresh_token grants of a token endpoint.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeTokenError(w, http.StatusMethodNotAllowed, "invalid_request", "expected POST", 0)
+		return
+	}
+	if !s.authenticate(r) {
+		s.writeTokenError(w, http.StatusUnauthorized, "invalid_client", "invalid client credentials", 0)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var issued *grant
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		code := s.codes[r.PostFormValue("code")]
+		delete(s.codes, r.PostFormValue("code"))
+		switch {
+		case code == nil || time.Now().After(code.expiry):
+			s.writeTokenError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired code", 0)
+			return
+		case code.redirectURI != r.PostFormValue("redirect_uri"):
+			s.writeTokenError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri mismatch", 0)
+			return
+		case code.challenge != "" && code.challenge != challenge(r.PostFormValue("code_verifier")):
+			s.writeTokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier mismatch", 0)
+			return
+		}
+		issued = code
+	case "refresh_token":
+		refresh := s.refreshTokens[r.PostFormValue("refresh_token")]
+		if refresh == nil {
+			s.writeTokenError(w, http.StatusBadRequest, "invalid_grant", "invalid refresh token", 0)
+			return
+		}
+		issued = refresh
+	default:
+		s.writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "", 0)
+		return
+	}
+
+	response := map[string]interface{}{"token_type": "Bearer", "expires_in": int(TokenLifetime / time.Second)}
+	access := randomString()
+	s.accessTokens[access] = &grant{clientID: issued.clientID, user: issued.user, expiry: time.Now().Add(TokenLifetime)}
+	response["access_token"] = access
+	if issued.scope != "" {
+		response["scope"] = issued.scope
+		if s.provider == "GitHub" {
+			response["scope"] = strings.Join(strings.Fields(issued.scope), ",")
+		}
+	}
+	if s.provider != "Facebook" && s.provider != "GitHub" {
+		refresh := r.PostFormValue("refresh_token")
+		if refresh == "" || s.rotateRefresh {
+			delete(s.refreshTokens, refresh)
+			refresh = randomString()
+			s.refreshTokens[refresh] = &grant{clientID: issued.clientID, user: issued.user}
+			response["refresh_token"] = refresh
+		}
+		idToken := s.signIDToken(issued, nil)
+		s.idTokens[idToken] = issued
+		response["id_token"] = idToken
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// revoke implements a revocation endpoint, the token being read from the given form parameters.
+func (s *Server) revoke(parameters ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, parameter := range parameters {
+			token := r.FormValue(parameter)
+			delete(s.accessTokens, token)
+			delete(s.refreshTokens, token)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// keys serves the JWKS of the ID token signing key.
+func (s *Server) keys(w http.ResponseWriter, _ *http.Request) {
+	jwk, err := jwks.NewJSONWebKey(s.kid, jwt.RS256, &s.key.PublicKey)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "server_error", err.Error(), 0)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []jwks.JSONWebKey{jwk}})
+}
+
+// userinfo serves the user of the bearer access token in the format of the provider.
+func (s *Server) userinfo(format func(User) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		access := s.bearer(r)
+		if access == nil {
+			s.writeError(w, http.StatusUnauthorized, "invalid_token", "invalid access token", 190)
+			return
+		}
+		writeJSON(w, http.StatusOK, format(access.user))
+	}
+}
+
+// bearer returns the grant of the access token of the request, from the Authorization header
+// or the access_token parameter, or nil if it is unknown or expired.
+func (s *Server) bearer(r *http.Request) *grant {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.FormValue("access_token")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	access := s.accessTokens[token]
+	if access == nil || time.Now().After(access.expiry) {
+		return nil
+	}
+	return access
+}
+
+// authenticate checks the client credentials sent with HTTP Basic authentication or in the body.
+func (s *Server) authenticate(r *http.Request) bool {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	return clientID == s.ClientID && (s.ClientSecret == "" || clientSecret == s.ClientSecret)
+}
+
+// signIDToken signs an ID token for the grant. The caller remembers it for the LINE verify endpoint.
+func (s *Server) signIDToken(issued *grant, extra map[string]interface{}) string {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   s.Issuer,
+		"aud":   issued.clientID,
+		"sub":   issued.user.Subject,
+		"iat":   now.Unix(),
+		"exp":   now.Add(TokenLifetime).Unix(),
+		"email": issued.user.Email,
+	}
+	if issued.nonce != "" {
+		claims["nonce"] = issued.nonce
+	}
+	switch s.provider {
+	case "Apple":
+		// Apple sends email_verified as a string.
+		claims["email_verified"] = strconv.FormatBool(issued.user.EmailVerified)
+	case "Microsoft":
+		// Microsoft has no email_verified claim, tokens name the tenant of the user.
+		claims["tid"] = s.tenantID
+		claims["oid"] = "00000000-0000-0000-" + issued.user.Subject
+		claims["preferred_username"] = issued.user.Email
+		claims["name"] = issued.user.Name
+		claims["ver"] = "2.0"
+	case "Kakao":
+		// Kakao has no email_verified claim, the profile is named after its consent items.
+		claims["nickname"] = issued.user.Name
+		claims["picture"] = issued.user.Picture
+	default:
+		claims["email_verified"] = issued.user.EmailVerified
+		claims["name"] = issued.user.Name
+		claims["picture"] = issued.user.Picture
+	}
+	for key, value := range extra {
+		claims[key] = value
+	}
+	token, err := jwt.Sign(jwt.RS256, s.kid, claims, s.key)
+	if err != nil {
+		panic("oauthtest: " + err.Error())
+	}
+	return token
+}
+
+// writeError writes an error in the format of the provider.
+func (s *Server) writeError(w http.ResponseWriter, status int, code, description string, errorCode int) {
+	switch s.provider {
+	case "Facebook":
+		if code == "" || strings.Contains(code, "_") {
+			code = "OAuthException"
+		}
+		writeJSON(w, status, map[string]interface{}{
+			"error": map[string]interface{}{"message": description, "type": code, "code": errorCode},
+		})
+	case "Line":
+		writeJSON(w, status, map[string]interface{}{"error": code, "error_description": description, "message": description})
+	case "GitHub":
+		if status == http.StatusOK {
+			writeJSON(w, status, map[string]interface{}{"error": code, "error_description": description})
+			return
+		}
+		writeJSON(w, status, map[string]interface{}{"message": description, "documentation_url": "https://docs.github.com/rest"})
+	case "Kakao":
+		if code == "invalid_token" {
+			errorCode = -401
+		} else if errorCode == 0 {
+			errorCode = -2
+		}
+		writeJSON(w, status, map[string]interface{}{"msg": description, "code": errorCode})
+	default:
+		writeJSON(w, status, map[string]interface{}{"error": code, "error_description": description})
+	}
+}
+
+// writeTokenError writes an error of the token endpoint. GitHub answers with a 200 status and its own codes,
+// Kakao with the OAuth format and a KOE error code.
+func (s *Server) writeTokenError(w http.ResponseWriter, status int, code, description string, errorCode int) {
+	if s.provider == "Kakao" {
+		response := map[string]interface{}{"error": code, "error_description": description}
+		switch code {
+		case "invalid_grant":
+			response["error_code"] = "KOE320"
+		case "invalid_client":
+			response["error_code"] = "KOE010"
+		}
+		writeJSON(w, status, response)
+		return
+	}
+	if s.provider == "GitHub" {
+		status = http.StatusOK
+		switch code {
+		case "invalid_grant":
+			code = "bad_verification_code"
+		case "invalid_client":
+			code = "incorrect_client_credentials"
+		}
+	}
+	s.writeError(w, status, code, description, errorCode)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// challenge returns the S256 code challenge of a PKCE verifier.
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns a random code or token. It is hex encoded, so that it never starts with "-"
+// and is not taken for a flag by command-line tools.
+func randomString() string {
+	data := make([]byte, 24)
+	if _, err := rand.Read(data); err != nil {
+		panic("oauthtest: " + err.Error())
+	}
+	return hex.EncodeToString(data)
+}
+
+// rewriteTransport sends every request to the target server, keeping its path and query.
+type rewriteTransport struct {
+	target string
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.target)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host, req.Host = target.Scheme, target.Host, target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
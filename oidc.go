@@ -0,0 +1,317 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+// OIDCDiscoveryPath is the path of the OpenID Provider configuration document, relative to the issuer.
+const OIDCDiscoveryPath = "/.well-known/openid-configuration"
+
+var (
+	ErrInvalidIssuer = errors.New("invalid issuer")
+	ErrInvalidNonce  = errors.New("invalid nonce")
+	ErrNotSupported  = errors.New("not supported by provider")
+)
+
+// OIDCDiscovery struct represents the OpenID Provider configuration document.
+//
+// documentation https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCClaims struct represents the standard claims of an ID token or userinfo response.
+type OIDCClaims struct {
+	// Issuer of the token
+	Iss string `json:"iss"`
+
+	// Subject of the token
+	Sub string `json:"sub"`
+
+	// Audience of the token
+	Aud jwt.Audience `json:"aud"`
+
+	// Authorized party
+	Azp string `json:"azp"`
+
+	// Expiration time of the token
+	Exp int64 `json:"exp"`
+
+	// Issued at time of the token
+	Iat int64 `json:"iat"`
+
+	// Time when the user authenticated
+	AuthTime int64 `json:"auth_time"`
+
+	// Nonce sent in the authorization request
+	Nonce string `json:"nonce"`
+
+	// Email address of the user
+	Email string `json:"email"`
+
+	// Indicates if the email is verified
+	EmailVerified StringBool `json:"email_verified"`
+
+	// Full name of the user
+	Name string `json:"name"`
+
+	// Given name of the user
+	GivenName string `json:"given_name"`
+
+	// Family name of the user
+	FamilyName string `json:"family_name"`
+
+	// Preferred username of the user
+	PreferredUsername string `json:"preferred_username"`
+
+	// Profile picture URL
+	Picture string `json:"picture"`
+
+	// Locale of the user
+	Locale string `json:"locale"`
+
+	// Raw All claims as returned by the provider
+	Raw map[string]interface{} `json:"-"`
+}
+
+// Identity converts the claims into the normalized Identity.
+func (c *OIDCClaims) Identity(provider AuthType) *Identity {
+	return &Identity{
+		Provider:      provider,
+		Subject:       c.Sub,
+		Email:         c.Email,
+		EmailVerified: bool(c.EmailVerified),
+		Name:          c.Name,
+		Picture:       c.Picture,
+		Raw:           c.Raw,
+	}
+}
+
+// OIDC struct represents a generic OpenID Connect provider configured from its discovery document.
+type OIDC struct {
+	service   *Service
+	discovery *OIDCDiscovery
+	keys      *jwks.Client
+}
+
+// NewOIDC creates a new instance of a generic OpenID Connect provider for the given issuer,
+// such as Keycloak, Okta, Auth0, Azure AD B2C or Cognito. The provider configuration is read
+// from the issuer's discovery document.
+func NewOIDC(ctx context.Context, service *Service, issuer string) (*OIDC, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	discovery := &OIDCDiscovery{}
+	err := service.request(issuer+OIDCDiscoveryPath, http.MethodGet,
+		WithTimeout(30*time.Second),
+		WithErrorDecoder(providerErrors(AuthOIDC, ErrInvalidIssuer)),
+	).DoJSON(ctx, discovery)
+	if err != nil {
+		return nil, err
+	}
+	return NewOIDCFromDiscovery(service, issuer, discovery)
+}
+
+// NewOIDCFromDiscovery creates a new instance of a generic OpenID Connect provider from an
+// already known discovery document. The document issuer must match issuer.
+func NewOIDCFromDiscovery(service *Service, issuer string, discovery *OIDCDiscovery) (*OIDC, error) {
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("%w: discovery document issuer %q does not match %q", ErrInvalidIssuer, discovery.Issuer, issuer)
+	}
+	if discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, fmt.Errorf("%w: discovery document lacks token_endpoint or jwks_uri", ErrInvalidIssuer)
+	}
+	if service.Endpoint == "" {
+		service.Endpoint = discovery.Issuer
+	}
+	return &OIDC{
+		service:   service,
+		discovery: discovery,
+		keys:      service.keys(AuthOIDC, service.url(EndpointKeys, discovery.Issuer, discovery.JwksURI)),
+	}, nil
+}
+
+// url returns the URL of the named endpoint, fallback being the one of the discovery document.
+func (p *OIDC) url(name, fallback string) string {
+	return p.service.url(name, p.discovery.Issuer, fallback)
+}
+
+// Discovery returns the provider configuration document.
+func (p *OIDC) Discovery() *OIDCDiscovery {
+	return p.discovery
+}
+
+// AuthCodeURL builds the authorization URL, requesting the openid, profile and email scopes by default.
+func (p *OIDC) AuthCodeURL(options ...AuthOption) (*AuthRequest, error) {
+	endpoint := p.url(EndpointAuthorize, p.discovery.AuthorizationEndpoint)
+	if endpoint == "" {
+		return nil, ErrNotSupported
+	}
+	return p.service.authCodeURL(endpoint, authDefaults{
+		scopes: []string{"openid", "profile", "email"},
+		nonce:  true,
+	}, options...)
+}
+
+// authStyle picks the token endpoint authentication method advertised by the provider,
+// client_secret_basic being the default of the specification.
+func (p *OIDC) authStyle() AuthStyle {
+	methods := p.discovery.TokenEndpointAuthMethodsSupported
+	if len(methods) == 0 || contains(methods, "client_secret_basic") {
+		return AuthStyleBasic
+	}
+	return AuthStylePost
+}
+
+// Exchange exchanges an authorization code for tokens at the token endpoint.
+// codeVerifier is the PKCE verifier of the authorization request, empty if PKCE was not used.
+func (p *OIDC) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	if code == "" {
+		return nil, ErrInvalidIdCode
+	}
+	params := codeParams(code, p.service.RedirectURL, codeVerifier)
+	return p.service.token(ctx, AuthOIDC, p.url(EndpointToken, p.discovery.TokenEndpoint), p.authStyle(), params, ErrInvalidIdCode)
+}
+
+// Refresh gets a new access token using a refresh token.
+func (p *OIDC) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return p.service.refresh(ctx, AuthOIDC, p.url(EndpointToken, p.discovery.TokenEndpoint), p.authStyle(), refreshToken)
+}
+
+// IDToken verifies an ID token: its signature against the provider keys, issuer, audience,
+// expiry and, when nonce is not empty, the nonce sent in the authorization request.
+func (p *OIDC) IDToken(ctx context.Context, token, nonce string) (*OIDCClaims, error) {
+	if token == "" {
+		return nil, ErrInvalidIdToken
+	}
+	verifier := &jwt.Verifier{
+		Algorithms: p.algorithms(),
+		Keys:       keyFunc(AuthOIDC, p.keys),
+		Issuers:    []string{p.discovery.Issuer},
+		Audiences:  p.service.audiences(),
+	}
+	claims := &OIDCClaims{}
+	parsed, err := verifier.Verify(ctx, token, claims)
+	if err != nil {
+		return nil, tokenError(AuthOIDC, err)
+	}
+	// The authorized party, when present, must be one of the accepted clients.
+	if err = p.service.authorizedParty(AuthOIDC, claims.Azp); err != nil {
+		return nil, err
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return nil, &TokenError{Provider: AuthOIDC, Kind: ErrInvalidIdToken, Err: ErrInvalidNonce}
+	}
+	claims.Raw = rawClaims(parsed.Payload)
+	return claims, nil
+}
+
+// Identify verifies the ID token of a code exchange and returns the user it identifies.
+// Without an ID token the user is read from the userinfo endpoint.
+func (p *OIDC) Identify(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	if token.IdToken == "" {
+		claims, err := p.UserInfo(ctx, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		return claims.Identity(AuthOIDC), nil
+	}
+	claims, err := p.IDToken(ctx, token.IdToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Identity(AuthOIDC), nil
+}
+
+// algorithms returns the ID token signing algorithms advertised by the provider, RS256 by default.
+func (p *OIDC) algorithms() []string {
+	var algorithms []string
+	for _, alg := range p.discovery.IDTokenSigningAlgValuesSupported {
+		if contains(jwt.AsymmetricAlgorithms, alg) {
+			algorithms = append(algorithms, alg)
+		}
+	}
+	if len(algorithms) == 0 {
+		return []string{jwt.RS256}
+	}
+	return algorithms
+}
+
+// UserInfo gets the claims about the user from the userinfo endpoint.
+func (p *OIDC) UserInfo(ctx context.Context, accessToken string) (*OIDCClaims, error) {
+	if accessToken == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	endpoint := p.url(EndpointUserInfo, p.discovery.UserinfoEndpoint)
+	if endpoint == "" {
+		return nil, ErrNotSupported
+	}
+	var raw map[string]interface{}
+	err := p.service.request(endpoint, http.MethodGet,
+		WithTimeout(30*time.Second),
+		WithHeader(http.Header{"Authorization": []string{"Bearer " + accessToken}}),
+		WithErrorDecoder(providerErrors(AuthOIDC, ErrInvalidAccessToken)),
+	).DoJSON(ctx, &raw)
+	if err != nil {
+		return nil, err
+	}
+	claims := &OIDCClaims{}
+	if err = remarshal(raw, claims); err != nil {
+		return nil, err
+	}
+	claims.Raw = raw
+	return claims, nil
+}
+
+// Revoke invalidates an access or refresh token at the revocation endpoint (RFC 7009),
+// authenticating the client as at the token endpoint.
+func (p *OIDC) Revoke(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidAccessToken
+	}
+	endpoint := p.url(EndpointRevoke, p.discovery.RevocationEndpoint)
+	if endpoint == "" {
+		return ErrNotSupported
+	}
+	params := url.Values{"token": []string{token}}
+	header, err := p.service.clientAuth(p.authStyle(), params)
+	if err != nil {
+		return err
+	}
+	return p.service.request(endpoint, http.MethodPost,
+		WithTimeout(30*time.Second),
+		WithContentType(ContentTypeWWWForm),
+		WithHeader(header),
+		WithData(params),
+		WithErrorDecoder(providerErrors(AuthOIDC, ErrInvalidAccessToken)),
+	).DoJSON(ctx, nil)
+}
+
+// contains reports whether values includes value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
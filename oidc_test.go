@@ -0,0 +1,151 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"socialgoauth.org/social-goauth/jwks"
+	"socialgoauth.org/social-goauth/jwt"
+)
+
+func TestOIDC(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	jwk, _ := jwks.NewJSONWebKey("k1", jwt.ES256, &key.PublicKey)
+
+	var server *httptest.Server
+	idToken := func(nonce string) string {
+		token, err := jwt.Sign(jwt.ES256, "k1", map[string]interface{}{
+			"iss": server.URL, "aud": "client", "sub": "user-1", "nonce": nonce,
+			"email": "rabbit@example.com", "email_verified": "true",
+			"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+		}, key)
+		if nil != err {
+			t.Fatal(err)
+		}
+		return token
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(OIDCDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(OIDCDiscovery{
+			Issuer:                           server.URL,
+			AuthorizationEndpoint:            server.URL + "/authorize",
+			TokenEndpoint:                    server.URL + "/token",
+			UserinfoEndpoint:                 server.URL + "/userinfo",
+			JwksURI:                          server.URL + "/keys",
+			IDTokenSigningAlgValuesSupported: []string{jwt.ES256},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwks.JSONWebKey{jwk}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if r.PostFormValue("code") != "good" && r.PostFormValue("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access", "token_type": "Bearer", "expires_in": "3600",
+			"refresh_token": "refresh", "id_token": idToken("n-1"),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"sub":"user-1","name":"Rabbit","custom":"value"}`))
+	})
+	mux.HandleFunc("/revoke", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" || r.PostFormValue("client_secret") != "" || r.PostFormValue("token") != "access" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	service, err := NewService("client", "secret", AuthOIDC, WithRedirectURL("https://example.com/callback"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	provider, err := NewOIDC(ctx, service, server.URL+"/")
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if _, err = provider.Exchange(ctx, "bad", ""); !errors.Is(err, ErrInvalidIdCode) {
+		t.Fatalf("expected ErrInvalidIdCode, got %v", err)
+	}
+	token, err := provider.Exchange(ctx, "good", "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access" || token.ExpiresIn != 3600 || token.Expiry.IsZero() {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	claims, err := provider.IDToken(ctx, token.IdToken, "n-1")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if claims.Sub != "user-1" || !claims.EmailVerified || claims.Raw["email"] != "rabbit@example.com" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if _, err = provider.IDToken(ctx, token.IdToken, "n-2"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("expected ErrInvalidNonce, got %v", err)
+	}
+
+	info, err := provider.UserInfo(ctx, token.AccessToken)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if info.Name != "Rabbit" || info.Raw["custom"] != "value" {
+		t.Fatalf("unexpected userinfo %+v", info)
+	}
+
+	refreshed, err := provider.Refresh(ctx, "refresh")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken != "refresh" {
+		t.Fatalf("unexpected refreshed token %+v", refreshed)
+	}
+	if err = provider.Revoke(ctx, "access"); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+
+	service, _ = NewService("client", "secret", AuthOIDC, WithEndpointURL(EndpointRevoke, server.URL+"/revoke"))
+	if provider, err = NewOIDC(ctx, service, server.URL+"/"); nil != err {
+		t.Fatal(err)
+	}
+	if err = provider.Revoke(ctx, "access"); nil != err {
+		t.Fatalf("expected the revocation to authenticate as the token endpoint: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+)
+
+// PKCEMethodS256 is the only code challenge method generated by this package.
+const PKCEMethodS256 = "S256"
+
+// PKCE is a Proof Key for Code Exchange pair (RFC 7636).
+// The Challenge is sent in the authorization URL and the Verifier in the code exchange.
+type PKCE struct {
+	// Verifier High-entropy secret kept by the client until the code exchange
+	Verifier string
+
+	// Challenge Derived from the verifier and sent in the authorization request
+	Challenge string
+
+	// Method Transformation used to derive the challenge, always S256
+	Method string
+}
+
+// NewPKCE generates a random code verifier and its S256 code challenge.
+func NewPKCE() (*PKCE, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	return &PKCE{Verifier: verifier, Challenge: CodeChallenge(verifier), Method: PKCEMethodS256}, nil
+}
+
+// CodeChallenge derives the S256 code challenge of a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// WithPKCE adds a freshly generated S256 code challenge to the authorization URL.
+// The matching verifier is returned in AuthRequest.CodeVerifier.
+func WithPKCE() AuthOption {
+	return func(params *authParams) {
+		params.pkce = true
+	}
+}
+
+// WithCodeVerifier adds the S256 code challenge of the given verifier to the authorization URL.
+func WithCodeVerifier(verifier string) AuthOption {
+	return func(params *authParams) {
+		params.pkce = true
+		params.codeVerifier = verifier
+	}
+}
+
+// codeParams returns the parameters of an authorization code grant, with the PKCE verifier when provided.
+func codeParams(code, redirectURL, codeVerifier string) url.Values {
+	params := url.Values{
+		"grant_type":   []string{"authorization_code"},
+		"code":         []string{code},
+		"redirect_uri": []string{redirectURL},
+	}
+	if codeVerifier != "" {
+		params.Set("code_verifier", codeVerifier)
+	}
+	return params
+}
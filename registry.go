@@ -0,0 +1,113 @@
+package oauth
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrProviderNotFound = errors.New("provider not found")
+
+// RegisteredProvider is a provider held by a Registry.
+type RegisteredProvider struct {
+	// Name Unique name of the provider, the AuthType unless several tenants of the same provider are registered
+	Name string
+
+	// Type Kind of provider, e.g. AuthGoogle or AuthOIDC
+	Type AuthType
+
+	// Provider Configured provider instance
+	Provider LoginProvider
+
+	// AuthOptions Options used for every authorization URL of the provider, e.g. its scopes
+	AuthOptions []AuthOption
+}
+
+// Registry holds the configured providers by name. It is safe for concurrent use,
+// providers can be registered and replaced while requests are served.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]*RegisteredProvider
+	order     []string
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]*RegisteredProvider{}}
+}
+
+// Register adds a provider under the name of its AuthType, replacing any provider with that name.
+func (r *Registry) Register(authType AuthType, provider LoginProvider, options ...AuthOption) error {
+	return r.RegisterNamed(string(authType), authType, provider, options...)
+}
+
+// RegisterNamed adds a provider under a custom name, e.g. "okta-acme" for one tenant of an OIDC provider.
+// A provider registered with the same name is replaced and keeps its position in List.
+func (r *Registry) RegisterNamed(name string, authType AuthType, provider LoginProvider, options ...AuthOption) error {
+	if name == "" || authType == "" || provider == nil {
+		return ErrInvalidProvider
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.providers[name] = &RegisteredProvider{Name: name, Type: authType, Provider: provider, AuthOptions: options}
+	return nil
+}
+
+// Remove removes the provider with the given name and reports whether it was registered.
+func (r *Registry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[name]; !ok {
+		return false
+	}
+	delete(r.providers, name)
+	for i, registered := range r.order {
+		if registered == name {
+			r.order = append(r.order[:i:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Get returns the provider with the given name, or ErrProviderNotFound.
+func (r *Registry) Get(name string) (LoginProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registered, ok := r.providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	return registered.Provider, nil
+}
+
+// Lookup returns the providers of the given AuthType in registration order.
+func (r *Registry) Lookup(authType AuthType) []RegisteredProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var providers []RegisteredProvider
+	for _, name := range r.order {
+		if registered := r.providers[name]; registered.Type == authType {
+			providers = append(providers, *registered)
+		}
+	}
+	return providers
+}
+
+// List returns every provider in registration order, e.g. to render a login page.
+func (r *Registry) List() []RegisteredProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make([]RegisteredProvider, 0, len(r.order))
+	for _, name := range r.order {
+		providers = append(providers, *r.providers[name])
+	}
+	return providers
+}
@@ -0,0 +1,47 @@
+package oauth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	google, _ := NewService("client", "secret", AuthGoogle)
+	acme, _ := NewService("acme", "secret", AuthOAuth2)
+	globex, _ := NewService("globex", "secret", AuthOAuth2)
+	acmeProvider, _ := NewOAuth2(acme, OAuth2Config{AuthURL: "https://acme.example.com/authorize", TokenURL: "https://acme.example.com/token"})
+	globexProvider, _ := NewOAuth2(globex, OAuth2Config{AuthURL: "https://globex.example.com/authorize", TokenURL: "https://globex.example.com/token"})
+
+	if err := registry.Register(AuthGoogle, nil); !errors.Is(err, ErrInvalidProvider) {
+		t.Fatalf("expected ErrInvalidProvider, got %v", err)
+	}
+	var wg sync.WaitGroup
+	for _, name := range []string{"acme", "globex"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			_ = registry.RegisterNamed(name, AuthOAuth2, acmeProvider)
+		}(name)
+	}
+	wg.Wait()
+	_ = registry.Register(AuthGoogle, NewGoogle(google))
+	_ = registry.RegisterNamed("globex", AuthOAuth2, globexProvider)
+
+	if provider, err := registry.Get("globex"); nil != err || provider != LoginProvider(globexProvider) {
+		t.Fatalf("expected the replaced provider, got %v %v", provider, err)
+	}
+	if providers := registry.Lookup(AuthOAuth2); len(providers) != 2 {
+		t.Fatalf("expected 2 OAuth2 providers, got %d", len(providers))
+	}
+	if providers := registry.List(); len(providers) != 3 || providers[2].Name != "Google" || providers[2].Type != AuthGoogle {
+		t.Fatalf("unexpected providers %+v", providers)
+	}
+	if !registry.Remove("acme") || registry.Remove("acme") {
+		t.Fatal("expected acme to be removed once")
+	}
+	if _, err := registry.Get("acme"); !errors.Is(err, ErrProviderNotFound) {
+		t.Fatalf("expected ErrProviderNotFound, got %v", err)
+	}
+}
@@ -2,11 +2,15 @@ package oauth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -38,10 +42,63 @@ type Request struct {
 
 	// Data contains the form values for the request body.
 	Data url.Values
+
+	// Body is an arbitrary request body. []byte and string values are sent as-is,
+	// anything else is encoded according to ContentType (JSON by default).
+	// Body takes precedence over Data.
+	Body interface{}
+
+	// Client is an optional HTTP client used to send the request.
+	// When nil, a shared pooled transport is used instead.
+	Client *http.Client
+
+	// Retry is an optional policy for retrying transient failures.
+	Retry *RetryPolicy
+
+	// Idempotent marks a non-idempotent method (e.g. a verification POST) as safe to retry.
+	Idempotent bool
+
+	// MaxResponseBytes limits the size of the response body read by DoJSON.
+	MaxResponseBytes int64
+
+	// ErrorDecoder converts unsuccessful responses into errors in DoJSON.
+	ErrorDecoder ErrorDecoder
 }
 
+// proxyTransports caches one pooled transport per proxy URL so that
+// connections are reused across requests.
+var proxyTransports sync.Map
+
 type ROption func(*Request)
 
+// RequestError describes a failure to build or send a Request.
+// It matches its Kind sentinel (for example ErrInvalidProxyURL) with errors.Is.
+type RequestError struct {
+	// Op is the step of the request pipeline that failed.
+	Op string
+
+	// URL is the request or proxy URL involved in the failure.
+	URL string
+
+	// Kind is the sentinel error classifying the failure.
+	Kind error
+
+	// Err is the underlying cause.
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s %q: %v: %v", e.Op, e.URL, e.Kind, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RequestError) Is(target error) bool {
+	return e.Kind == target
+}
+
 // WithContentType sets the ContentType option for the Request.
 func WithContentType(contentType ContentType) ROption {
 	return func(request *Request) {
@@ -70,57 +127,158 @@ func WithData(data url.Values) ROption {
 	}
 }
 
-// formatParams converts the request data to the appropriate format based on the content type.
-func (req *Request) formatParams() io.Reader {
+// WithBody sets the Body option for the Request.
+func WithBody(body interface{}) ROption {
+	return func(request *Request) {
+		request.Body = body
+	}
+}
+
+// WithClient sets the Client option for the Request.
+func WithClient(client *http.Client) ROption {
+	return func(request *Request) {
+		request.Client = client
+	}
+}
+
+// WithRetry sets the Retry option for the Request.
+func WithRetry(policy *RetryPolicy) ROption {
+	return func(request *Request) {
+		request.Retry = policy
+	}
+}
+
+// WithIdempotent marks the Request as safe to retry regardless of its method.
+func WithIdempotent() ROption {
+	return func(request *Request) {
+		request.Idempotent = true
+	}
+}
+
+// formatParams converts the request body or data to the appropriate format based on the content type.
+// When ContentType is empty it is taken from the Content-Type header, and otherwise defaults
+// to JSON for Body and to a URL-encoded form for Data.
+func (req *Request) formatParams() (io.Reader, error) {
+	if req.ContentType == "" {
+		if v := req.Header.Get("Content-Type"); v != "" {
+			req.ContentType = ContentType(v)
+		}
+	}
+
+	if req.Body != nil {
+		switch body := req.Body.(type) {
+		case []byte:
+			return bytes.NewReader(body), nil
+		case string:
+			return strings.NewReader(body), nil
+		}
+		if req.ContentType == "" {
+			req.ContentType = ContentTypeJson
+		}
+		return req.encode(req.Body)
+	}
+
 	if len(req.Data) > 0 {
 		if req.ContentType == "" {
-			if v, ok := req.Header["Content-Type"]; ok {
-				req.ContentType = ContentType(v[0])
-			}
+			req.ContentType = ContentTypeWWWForm
+		}
+		if req.ContentType.mediaType() == ContentTypeJson.mediaType() {
+			return req.encode(flattenValues(req.Data))
+		}
+		return req.encode(req.Data)
+	}
+	return nil, nil
+}
+
+// encode serializes a body value according to the request's content type.
+func (req *Request) encode(body interface{}) (io.Reader, error) {
+	switch req.ContentType.mediaType() {
+	case ContentTypeJson.mediaType():
+		value, err := json.Marshal(body)
+		if err != nil {
+			return nil, &RequestError{Op: "encode", URL: req.URL, Kind: ErrInvalidRequestBody, Err: err}
 		}
+		return bytes.NewReader(value), nil
+	case ContentTypeWWWForm.mediaType():
+		values, ok := body.(url.Values)
+		if !ok {
+			return nil, &RequestError{Op: "encode", URL: req.URL, Kind: ErrInvalidRequestBody,
+				Err: fmt.Errorf("cannot encode %T as a form", body)}
+		}
+		return strings.NewReader(values.Encode()), nil
+	}
+	return nil, &RequestError{Op: "encode", URL: req.URL, Kind: ErrInvalidRequestBody,
+		Err: fmt.Errorf("unsupported content type %q", req.ContentType)}
+}
 
-		switch req.ContentType {
-		case ContentTypeJson:
-			value, err := json.Marshal(req.Data)
-			if err != nil {
-				panic(err)
-			}
-			return bytes.NewReader(value)
-		case ContentTypeWWWForm:
-			return strings.NewReader(req.Data.Encode())
+// flattenValues converts form values to a JSON object, using plain strings for single values.
+func flattenValues(values url.Values) map[string]interface{} {
+	object := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if len(value) == 1 {
+			object[key] = value[0]
+		} else {
+			object[key] = value
 		}
 	}
-	return nil
+	return object
+}
+
+// mediaType returns the content type without its parameters, e.g. "application/json".
+func (c ContentType) mediaType() string {
+	mediaType, _, err := mime.ParseMediaType(string(c))
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(string(c), ";")[0]))
+	}
+	return mediaType
 }
 
 // newRequest creates a new http.Request based on the Request parameters.
-func (req *Request) newRequest() *http.Request {
-	request, err := http.NewRequest(req.Method, req.URL, req.formatParams())
+func (req *Request) newRequest(ctx context.Context) (*http.Request, error) {
+	body, err := req.formatParams()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	request.Header = req.Header
-	return request
+	request, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, &RequestError{Op: "build", URL: req.URL, Kind: ErrInvalidRequestURL, Err: err}
+	}
+	request.Header = req.Header.Clone()
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+	if body != nil && req.ContentType != "" && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", string(req.ContentType))
+	}
+	return request, nil
 }
 
-// setProxy configures the proxy for the request, if a ProxyURL is provided.
-func (req *Request) setProxy() *http.Transport {
-	var proxy *http.Transport = nil
-	if req.ProxyURL != "" {
-		u, err := url.Parse(req.ProxyURL)
-		if err != nil {
-			panic(err)
-		}
-		proxy = &http.Transport{
-			Proxy: http.ProxyURL(u),
-		}
+// transport returns the shared transport for the request, honoring ProxyURL if provided.
+func (req *Request) transport() (http.RoundTripper, error) {
+	if req.ProxyURL == "" {
+		return http.DefaultTransport, nil
+	}
+	if t, ok := proxyTransports.Load(req.ProxyURL); ok {
+		return t.(*http.Transport), nil
+	}
+	u, err := parseProxyURL(req.ProxyURL)
+	if err != nil {
+		return nil, &RequestError{Op: "proxy", URL: req.ProxyURL, Kind: ErrInvalidProxyURL, Err: err}
 	}
-	return proxy
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyURL(u)
+	actual, _ := proxyTransports.LoadOrStore(req.ProxyURL, t)
+	return actual.(*http.Transport), nil
 }
 
 // httpClient returns an HTTP client based on the given request configuration.
-func (req *Request) httpClient() http.Client {
-	client := http.Client{}
+// An injected Client keeps its transport, jar and redirect policy, but its
+// Timeout is replaced by the request's Timeout.
+func (req *Request) httpClient() (*http.Client, error) {
+	client := &http.Client{}
+	if req.Client != nil {
+		*client = *req.Client
+	}
 
 	// If no timeout value is specified in the request, set the timeout value for the client to default to 5 seconds.
 	if 0 >= req.Timeout {
@@ -128,18 +286,51 @@ func (req *Request) httpClient() http.Client {
 	}
 	client.Timeout = req.Timeout
 
-	// Check for proxy configuration and set the client's transport accordingly.
-	if proxy := req.setProxy(); proxy != nil {
-		client.Transport = proxy
+	// Fall back to the shared transport when the client does not bring its own.
+	if client.Transport == nil {
+		transport, err := req.transport()
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
 	}
 
-	return client
+	return client, nil
 }
 
 // Do Execute an HTTP request and return the response
 func (req *Request) Do() (*http.Response, error) {
-	client := req.httpClient()
-	return client.Do(req.newRequest())
+	return req.DoContext(context.Background())
+}
+
+// DoContext Execute an HTTP request bound to ctx and return the response.
+// Transient failures are retried according to the request's RetryPolicy.
+func (req *Request) DoContext(ctx context.Context) (*http.Response, error) {
+	client, err := req.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	attempts := req.attempts()
+	for attempt := 1; ; attempt++ {
+		request, err := req.newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(request)
+		if attempt >= attempts || !req.Retry.retryable(ctx, resp, err) {
+			return resp, err
+		}
+		delay, ok := req.Retry.backoff(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			discard(resp)
+		}
+		if err := wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
 }
 
 // Post Execute an POST request and return the response.
@@ -0,0 +1,109 @@
+package oauth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type countingTransport struct {
+	count int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRequestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	service, err := NewService("client", "secret", AuthLine, WithTransport(transport))
+	if nil != err {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := service.request(server.URL, http.MethodGet).Do()
+		if nil != err {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if transport.count != 2 {
+		t.Fatalf("expected 2 round trips through the injected transport, got %d", transport.count)
+	}
+
+	a, err := New(server.URL, http.MethodGet, "http://127.0.0.1:8001").transport()
+	if nil != err {
+		t.Fatal(err)
+	}
+	b, err := New(server.URL, http.MethodGet, "http://127.0.0.1:8001").transport()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("expected requests with the same proxy to share a transport")
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	if _, err := New("http://example.com", http.MethodGet, "::bad").Do(); !errors.Is(err, ErrInvalidProxyURL) {
+		t.Fatalf("expected ErrInvalidProxyURL, got %v", err)
+	}
+	if _, err := New("http://exa mple.com/\x7f", "BAD METHOD", "").Do(); !errors.Is(err, ErrInvalidRequestURL) {
+		t.Fatalf("expected ErrInvalidRequestURL, got %v", err)
+	}
+
+	if _, err := NewService("client", "secret", AuthLine, WithProxyURL("127.0.0.1:8001")); !errors.Is(err, ErrInvalidProxyURL) {
+		t.Fatalf("expected ErrInvalidProxyURL, got %v", err)
+	}
+	if _, err := NewService("client", "secret", AuthLine, WithRedirectURL("/callback")); !errors.Is(err, ErrInvalidRedirectURL) {
+		t.Fatalf("expected ErrInvalidRedirectURL, got %v", err)
+	}
+	if _, err := NewService("client", "secret", AuthLine, WithRedirectURL("https://example.com/callback")); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	var contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, _ := io.ReadAll(r.Body)
+		contentType, body = r.Header.Get("Content-Type"), string(value)
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		options     []ROption
+		contentType string
+		body        string
+	}{
+		{[]ROption{WithBody(map[string]int{"a": 1})}, string(ContentTypeJson), `{"a":1}`},
+		{[]ROption{WithContentType(ContentTypeJson), WithData(url.Values{"a": {"1"}, "b": {"2", "3"}})}, string(ContentTypeJson), `{"a":"1","b":["2","3"]}`},
+		{[]ROption{WithData(url.Values{"a": {"1"}})}, string(ContentTypeWWWForm), `a=1`},
+		{[]ROption{WithBody([]byte("raw")), WithContentType("text/plain")}, "text/plain", `raw`},
+		{[]ROption{WithBody(struct{}{}), WithHeader(http.Header{"Content-Type": {"application/json"}})}, "application/json", `{}`},
+	}
+	for _, c := range cases {
+		resp, err := New(server.URL, http.MethodPost, "", c.options...).Do()
+		if nil != err {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if contentType != c.contentType || body != c.body {
+			t.Fatalf("expected %q %q, got %q %q", c.contentType, c.body, contentType, body)
+		}
+	}
+
+	_, err := New(server.URL, http.MethodPost, "", WithBody(map[string]int{}), WithContentType(ContentTypeWWWForm)).Do()
+	if !errors.Is(err, ErrInvalidRequestBody) {
+		t.Fatalf("expected ErrInvalidRequestBody, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxResponseBytes is the response size limit used by DoJSON when MaxResponseBytes is zero.
+const DefaultMaxResponseBytes = 1 << 20
+
+var (
+	ErrResponseTooLarge       = errors.New("response body too large")
+	ErrUnexpectedContentType  = errors.New("unexpected response content type")
+	ErrUnexpectedResponseCode = errors.New("unexpected response status code")
+)
+
+// ErrorDecoder converts an unsuccessful response and its body into an error.
+type ErrorDecoder func(resp *http.Response, body []byte) error
+
+// ResponseError is returned by DoJSON when a response cannot be decoded.
+// It keeps the response body for diagnostics.
+type ResponseError struct {
+	// StatusCode HTTP status code of the response.
+	StatusCode int
+
+	// ContentType Content-Type header of the response.
+	ContentType string
+
+	// Body Raw response body, truncated to the request's MaxResponseBytes.
+	Body []byte
+
+	// Err Underlying cause.
+	Err error
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%v (status %d, content type %q)", e.Err, e.StatusCode, e.ContentType)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// WithMaxResponseBytes sets the MaxResponseBytes option for the Request.
+func WithMaxResponseBytes(limit int64) ROption {
+	return func(request *Request) {
+		request.MaxResponseBytes = limit
+	}
+}
+
+// WithErrorDecoder sets the ErrorDecoder option for the Request.
+func WithErrorDecoder(decoder ErrorDecoder) ROption {
+	return func(request *Request) {
+		request.ErrorDecoder = decoder
+	}
+}
+
+// providerErrors returns an ErrorDecoder producing a ProviderError for the given provider.
+// kind is the sentinel describing what the call was validating.
+func providerErrors(provider AuthType, kind error) ErrorDecoder {
+	return func(resp *http.Response, body []byte) error {
+		return newProviderError(provider, resp, body, kind)
+	}
+}
+
+// DoJSON Execute an HTTP request and decode its JSON response into out.
+//
+// Non-2xx responses are converted to errors by the request's ErrorDecoder. The response
+// body is limited to MaxResponseBytes and must be JSON; out may be nil to only check the status.
+func (req *Request) DoJSON(ctx context.Context, out interface{}) error {
+	resp, err := req.DoContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	limit := req.MaxResponseBytes
+	if limit <= 0 {
+		limit = DefaultMaxResponseBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if int64(len(body)) > limit {
+		return &ResponseError{StatusCode: resp.StatusCode, ContentType: contentType, Body: body[:limit], Err: ErrResponseTooLarge}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if req.ErrorDecoder != nil {
+			return req.ErrorDecoder(resp, body)
+		}
+		return &ResponseError{StatusCode: resp.StatusCode, ContentType: contentType, Body: body, Err: ErrUnexpectedResponseCode}
+	}
+
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if contentType != "" && !isJSON(contentType) {
+		return &ResponseError{StatusCode: resp.StatusCode, ContentType: contentType, Body: body, Err: ErrUnexpectedContentType}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return &ResponseError{StatusCode: resp.StatusCode, ContentType: contentType, Body: body, Err: err}
+	}
+	return nil
+}
+
+// isJSON reports whether a Content-Type header denotes a JSON document.
+func isJSON(contentType string) bool {
+	mediaType := ContentType(contentType).mediaType()
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
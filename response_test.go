@@ -0,0 +1,64 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"sub":"U1","name":"rabbit"}`))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html></html>`))
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`"` + strings.Repeat("a", 64) + `"`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"access token expired"}`))
+		}
+	}))
+	defer server.Close()
+
+	var info LineUserInformation
+	if err := New(server.URL+"/ok", http.MethodGet, "").DoJSON(context.Background(), &info); nil != err {
+		t.Fatal(err)
+	}
+	if info.Sub != "U1" || info.Name != "rabbit" {
+		t.Fatalf("unexpected decoded value: %+v", info)
+	}
+
+	err := New(server.URL+"/fail", http.MethodGet, "",
+		WithErrorDecoder(providerErrors(AuthLine, ErrInvalidAccessToken)),
+	).DoJSON(context.Background(), &info)
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || !errors.Is(err, ErrInvalidAccessToken) || providerErr.Description != "access token expired" {
+		t.Fatalf("expected a provider error, got %v", err)
+	}
+
+	err = New(server.URL+"/fail", http.MethodGet, "").DoJSON(context.Background(), &info)
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || !errors.Is(err, ErrUnexpectedResponseCode) || len(responseErr.Body) == 0 {
+		t.Fatalf("expected a response error with the body, got %v", err)
+	}
+
+	err = New(server.URL+"/html", http.MethodGet, "").DoJSON(context.Background(), &info)
+	if !errors.Is(err, ErrUnexpectedContentType) {
+		t.Fatalf("expected ErrUnexpectedContentType, got %v", err)
+	}
+
+	var value string
+	err = New(server.URL+"/large", http.MethodGet, "", WithMaxResponseBytes(16)).DoJSON(context.Background(), &value)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Default values used when the corresponding RetryPolicy field is zero.
+const (
+	DefaultRetryBaseDelay = 200 * time.Millisecond
+	DefaultRetryMaxDelay  = 5 * time.Second
+)
+
+// RetryPolicy describes how a Request is retried after transient failures.
+//
+// Only idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE) and requests explicitly
+// marked safe with WithIdempotent are retried. Connection errors and 429, 500, 502,
+// 503 and 504 responses are considered transient.
+type RetryPolicy struct {
+	// MaxAttempts Total number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay Delay before the first retry, doubled on every following attempt.
+	BaseDelay time.Duration
+
+	// MaxDelay Upper bound for a single delay. A Retry-After longer than MaxDelay stops retrying.
+	MaxDelay time.Duration
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns the delay before the given retry attempt (starting at 1),
+// using exponential backoff with jitter and honoring the Retry-After header.
+// The boolean result is false when the provider asked to wait longer than MaxDelay.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	if max <= 0 {
+		max = DefaultRetryMaxDelay
+	}
+
+	if resp != nil {
+		if after := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); after > 0 {
+			return after, after <= max
+		}
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	// Equal jitter: wait between half and the full computed delay.
+	half := int64(delay / 2)
+	jitterMu.Lock()
+	delay = time.Duration(half + jitterRand.Int63n(half+1))
+	jitterMu.Unlock()
+	return delay, true
+}
+
+// retryable reports whether the outcome of an attempt is a transient failure.
+func (p *RetryPolicy) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		// Every error of http.Client.Do is a net.Error (*url.Error): only timeouts and broken connections
+		// are transient, not e.g. certificate errors or an unsupported scheme.
+		var netErr net.Error
+		return (errors.As(err, &netErr) && netErr.Timeout()) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// attempts returns the number of attempts allowed for the request.
+func (req *Request) attempts() int {
+	if req.Retry == nil || req.Retry.MaxAttempts < 2 {
+		return 1
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Retry.MaxAttempts
+	}
+	if req.Idempotent {
+		return req.Retry.MaxAttempts
+	}
+	return 1
+}
+
+// wait sleeps for the given delay or until the context is done.
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discard drains and closes a response body so its connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+}
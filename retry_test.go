@@ -0,0 +1,93 @@
+package oauth
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestRetry(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	resp, err := New(server.URL, http.MethodGet, "", WithRetry(policy)).Do()
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Fatalf("expected success after 3 calls, got status %d after %d calls", resp.StatusCode, calls)
+	}
+
+	calls = 0
+	resp, err = New(server.URL, http.MethodPost, "", WithRetry(policy)).Do()
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Fatalf("expected POST not to be retried, got %d calls", calls)
+	}
+
+	calls = 0
+	resp, err = New(server.URL, http.MethodPost, "", WithRetry(policy), WithIdempotent()).Do()
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Fatalf("expected idempotent POST to be retried, got %d calls", calls)
+	}
+}
+
+func TestRetryAfterBeyondMaxDelay(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, MaxDelay: time.Second}
+	resp, err := New(server.URL, http.MethodGet, "", WithRetry(policy)).Do()
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Fatalf("expected no retry when Retry-After exceeds MaxDelay, got %d calls", calls)
+	}
+}
+
+func TestRetryCertificateError(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	if _, err := New(server.URL, http.MethodGet, "", WithRetry(policy)).Do(); err == nil {
+		t.Fatal("expected a certificate error")
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Fatalf("expected a certificate error not to be retried, got %d connections", n)
+	}
+}
@@ -0,0 +1,334 @@
+package oauth
+
+import (
+	"container/heap"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStateTTL is how long a login state is valid when the store does not set a TTL.
+	DefaultStateTTL = 10 * time.Minute
+
+	// DefaultStateStoreSize is the number of pending logins kept by a MemoryStateStore when no size is set.
+	DefaultStateStoreSize = 10000
+
+	// DefaultStateCookiePrefix is the name prefix of the cookies written by a CookieStateStore.
+	DefaultStateCookiePrefix = "oauth_state_"
+)
+
+var (
+	ErrInvalidState    = errors.New("invalid login state")
+	ErrStateNotFound   = errors.New("login state not found, expired or already used")
+	ErrInvalidStateKey = errors.New("invalid state key, must be 16, 24 or 32 bytes")
+)
+
+// LoginState is what must be kept between the redirect to the provider and the callback.
+type LoginState struct {
+	// State Value sent in the authorization URL and echoed back in the callback
+	State string `json:"state"`
+
+	// Nonce Value expected in the ID token, empty for providers without ID tokens
+	Nonce string `json:"nonce,omitempty"`
+
+	// CodeVerifier PKCE verifier to send with the code exchange
+	CodeVerifier string `json:"code_verifier,omitempty"`
+
+	// Provider Name of the provider the login was started with
+	Provider string `json:"provider,omitempty"`
+
+	// RedirectTo Local URL the user is sent to after the login
+	RedirectTo string `json:"redirect_to,omitempty"`
+
+	// ExpiresAt Time after which the state is rejected, set by the store when zero
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewLoginState returns the LoginState to save for an authorization request.
+func NewLoginState(provider string, request *AuthRequest) *LoginState {
+	return &LoginState{
+		State:        request.State,
+		Nonce:        request.Nonce,
+		CodeVerifier: request.CodeVerifier,
+		Provider:     provider,
+	}
+}
+
+// StateStore keeps login states between the redirect and the callback.
+// A state can be consumed only once, and is rejected once expired.
+type StateStore interface {
+	// Save stores the login state, before the user is redirected to the provider.
+	Save(w http.ResponseWriter, r *http.Request, state *LoginState) error
+
+	// Consume returns and removes the login state of the callback, or ErrStateNotFound.
+	Consume(w http.ResponseWriter, r *http.Request, state string) (*LoginState, error)
+}
+
+// MemoryStateStore is a StateStore kept in the memory of the process.
+// It only works when the callback is served by the same instance as the login.
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	states  map[string]*LoginState
+	expiry  stateHeap
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+}
+
+// NewMemoryStateStore creates a MemoryStateStore keeping at most maxSize pending logins for ttl.
+// The pending logins closest to expiry are dropped when the store is full.
+func NewMemoryStateStore(ttl time.Duration, maxSize int) *MemoryStateStore {
+	if ttl <= 0 {
+		ttl = DefaultStateTTL
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultStateStoreSize
+	}
+	return &MemoryStateStore{states: map[string]*LoginState{}, ttl: ttl, maxSize: maxSize, now: time.Now}
+}
+
+// Save implements StateStore.
+func (s *MemoryStateStore) Save(_ http.ResponseWriter, _ *http.Request, state *LoginState) error {
+	if state == nil || state.State == "" {
+		return ErrInvalidState
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	saved := *state
+	if saved.ExpiresAt.IsZero() {
+		saved.ExpiresAt = now.Add(s.ttl)
+	}
+	if previous, ok := s.states[saved.State]; ok {
+		s.remove(previous)
+	}
+	for len(s.expiry) > 0 && (len(s.states) >= s.maxSize || !s.expiry[0].ExpiresAt.After(now)) {
+		delete(s.states, heap.Pop(&s.expiry).(*LoginState).State)
+	}
+	s.states[saved.State] = &saved
+	heap.Push(&s.expiry, &saved)
+	return nil
+}
+
+// Consume implements StateStore.
+func (s *MemoryStateStore) Consume(_ http.ResponseWriter, _ *http.Request, state string) (*LoginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, ok := s.states[state]
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+	s.remove(saved)
+	if !saved.ExpiresAt.After(s.now()) {
+		return nil, ErrStateNotFound
+	}
+	result := *saved
+	return &result, nil
+}
+
+// remove drops a pending login from the store.
+func (s *MemoryStateStore) remove(saved *LoginState) {
+	delete(s.states, saved.State)
+	for i, pending := range s.expiry {
+		if pending == saved {
+			heap.Remove(&s.expiry, i)
+			return
+		}
+	}
+}
+
+// Len returns the number of pending logins, including expired ones not dropped yet.
+func (s *MemoryStateStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.states)
+}
+
+// stateHeap orders pending logins by expiry so the oldest are dropped first.
+type stateHeap []*LoginState
+
+func (h stateHeap) Len() int            { return len(h) }
+func (h stateHeap) Less(i, j int) bool  { return h[i].ExpiresAt.Before(h[j].ExpiresAt) }
+func (h stateHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *stateHeap) Push(x interface{}) { *h = append(*h, x.(*LoginState)) }
+func (h *stateHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return last
+}
+
+// CookieStateStore is a stateless StateStore keeping each login state in an AES-GCM encrypted cookie.
+// Keys can be rotated by prepending the new key: the first key encrypts, every key decrypts.
+//
+// The cookie is removed on the callback, but a copy captured before cannot be revoked until it expires.
+// Providers posting the callback cross-site (Apple form_post) need SameSite set to http.SameSiteNoneMode.
+type CookieStateStore struct {
+	// Prefix Name prefix of the cookies, DefaultStateCookiePrefix when empty
+	Prefix string
+
+	// Path Cookie path, "/" when empty
+	Path string
+
+	// Domain Cookie domain, the host of the request when empty
+	Domain string
+
+	// Secure Only send the cookies over HTTPS
+	Secure bool
+
+	// SameSite Cookie SameSite attribute, http.SameSiteLaxMode when zero
+	SameSite http.SameSite
+
+	// TTL Lifetime of the login states, DefaultStateTTL when zero
+	TTL time.Duration
+
+	aeads []cipher.AEAD
+	now   func() time.Time
+}
+
+// NewCookieStateStore creates a CookieStateStore with AES keys of 16, 24 or 32 bytes, the current key first.
+func NewCookieStateStore(keys ...[]byte) (*CookieStateStore, error) {
+	aeads, err := newAEADs(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &CookieStateStore{Secure: true, aeads: aeads, now: time.Now}, nil
+}
+
+// Save implements StateStore.
+func (s *CookieStateStore) Save(w http.ResponseWriter, _ *http.Request, state *LoginState) error {
+	if state == nil || state.State == "" {
+		return ErrInvalidState
+	}
+	saved := *state
+	if saved.ExpiresAt.IsZero() {
+		ttl := s.TTL
+		if ttl <= 0 {
+			ttl = DefaultStateTTL
+		}
+		saved.ExpiresAt = s.now().Add(ttl)
+	}
+	data, err := json.Marshal(&saved)
+	if err != nil {
+		return err
+	}
+	name := s.cookieName(saved.State)
+	value, err := seal(s.aeads[0], data, []byte(name))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, s.cookie(name, value, saved.ExpiresAt))
+	return nil
+}
+
+// Consume implements StateStore.
+func (s *CookieStateStore) Consume(w http.ResponseWriter, r *http.Request, state string) (*LoginState, error) {
+	name := s.cookieName(state)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, ErrStateNotFound
+	}
+	http.SetCookie(w, s.cookie(name, "", time.Unix(0, 0)))
+
+	data, err := unseal(s.aeads, cookie.Value, []byte(name))
+	if err != nil {
+		return nil, ErrStateNotFound
+	}
+	saved := &LoginState{}
+	if err = json.Unmarshal(data, saved); err != nil || saved.State != state || !saved.ExpiresAt.After(s.now()) {
+		return nil, ErrStateNotFound
+	}
+	return saved, nil
+}
+
+// cookieName derives the cookie name from the state, so that concurrent logins do not overwrite each other.
+func (s *CookieStateStore) cookieName(state string) string {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = DefaultStateCookiePrefix
+	}
+	sum := sha256.Sum256([]byte(state))
+	return prefix + hex.EncodeToString(sum[:8])
+}
+
+func (s *CookieStateStore) cookie(name, value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		Expires:  expires,
+		Secure:   s.Secure,
+		HttpOnly: true,
+		SameSite: s.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// newAEADs creates an AES-GCM cipher for each key.
+func newAEADs(keys [][]byte) ([]cipher.AEAD, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidStateKey
+	}
+	aeads := make([]cipher.AEAD, 0, len(keys))
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStateKey, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads = append(aeads, aead)
+	}
+	return aeads, nil
+}
+
+// seal encrypts data with a random nonce and returns the nonce and ciphertext encoded with unpadded base64url.
+func seal(aead cipher.AEAD, data, additional []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, data, additional)), nil
+}
+
+// unseal decrypts a value produced by seal with the first key that authenticates it.
+func unseal(aeads []cipher.AEAD, value string, additional []byte) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, aead := range aeads {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+		if plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additional); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, errors.New("no key decrypts the value")
+}
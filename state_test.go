@@ -0,0 +1,94 @@
+package oauth
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryStateStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStateStore(time.Minute, 2)
+	store.now = func() time.Time { return now }
+
+	for _, state := range []string{"a", "b", "c"} {
+		if err := store.Save(nil, nil, &LoginState{State: state, Nonce: "nonce-" + state}); nil != err {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+	if store.Len() != 2 {
+		t.Fatalf("expected the store to be bounded to 2, got %d", store.Len())
+	}
+	if _, err := store.Consume(nil, nil, "a"); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected the oldest state to be dropped, got %v", err)
+	}
+	state, err := store.Consume(nil, nil, "b")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if state.Nonce != "nonce-b" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if _, err = store.Consume(nil, nil, "b"); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected a state to be consumed once, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err = store.Consume(nil, nil, "c"); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected an expired state to be rejected, got %v", err)
+	}
+}
+
+func TestCookieStateStore(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	if _, err := NewCookieStateStore([]byte("short")); !errors.Is(err, ErrInvalidStateKey) {
+		t.Fatalf("expected ErrInvalidStateKey, got %v", err)
+	}
+	previous, _ := NewCookieStateStore(oldKey)
+	store, err := NewCookieStateStore(newKey, oldKey)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// A state saved before the key rotation is still accepted.
+	recorder := httptest.NewRecorder()
+	if err = previous.Save(recorder, nil, &LoginState{State: "state", CodeVerifier: "verifier"}); nil != err {
+		t.Fatal(err)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	callback := httptest.NewRequest(http.MethodGet, "/callback?state=state", nil)
+	callback.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+	state, err := store.Consume(recorder, callback, "state")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if state.CodeVerifier != "verifier" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if removed := recorder.Result().Cookies(); len(removed) != 1 || removed[0].MaxAge >= 0 {
+		t.Fatalf("expected the cookie to be removed, got %v", removed)
+	}
+
+	// A cookie is bound to its state and rejected once expired or tampered with.
+	recorder = httptest.NewRecorder()
+	_ = store.Save(recorder, nil, &LoginState{State: "other"})
+	cookie := recorder.Result().Cookies()[0]
+	callback = httptest.NewRequest(http.MethodGet, "/callback", nil)
+	callback.AddCookie(&http.Cookie{Name: store.cookieName("state"), Value: cookie.Value})
+	if _, err = store.Consume(httptest.NewRecorder(), callback, "state"); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected a cookie of another state to be rejected, got %v", err)
+	}
+	store.now = func() time.Time { return time.Now().Add(DefaultStateTTL) }
+	callback = httptest.NewRequest(http.MethodGet, "/callback", nil)
+	callback.AddCookie(cookie)
+	if _, err = store.Consume(httptest.NewRecorder(), callback, "other"); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected an expired state to be rejected, got %v", err)
+	}
+}
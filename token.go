@@ -0,0 +1,186 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AuthStyle describes how the client authenticates to a token endpoint.
+type AuthStyle int
+
+const (
+	// AuthStyleBasic sends the client credentials with HTTP Basic authentication (client_secret_basic).
+	AuthStyleBasic AuthStyle = iota
+
+	// AuthStylePost sends the client credentials in the request body (client_secret_post).
+	AuthStylePost
+)
+
+// UnmarshalText parses an AuthStyle from "basic" (client_secret_basic) or "post" (client_secret_post).
+func (s *AuthStyle) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "basic", "header", "client_secret_basic":
+		*s = AuthStyleBasic
+	case "post", "params", "client_secret_post":
+		*s = AuthStylePost
+	default:
+		return fmt.Errorf("unknown auth style %q", text)
+	}
+	return nil
+}
+
+// MarshalText formats an AuthStyle as "basic" or "post".
+func (s AuthStyle) MarshalText() ([]byte, error) {
+	if s == AuthStylePost {
+		return []byte("post"), nil
+	}
+	return []byte("basic"), nil
+}
+
+// Token is the normalized response of an OAuth 2.0 token endpoint.
+type Token struct {
+	// Access token used to call the provider APIs
+	AccessToken string `json:"access_token"`
+
+	// Type of the access token, usually "Bearer"
+	TokenType string `json:"token_type,omitempty"`
+
+	// Refresh token used to obtain new access tokens
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// OpenID Connect ID token
+	IdToken string `json:"id_token,omitempty"`
+
+	// Scopes granted to the access token, space separated
+	Scope string `json:"scope,omitempty"`
+
+	// Lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+
+	// Absolute expiry of the access token, computed from ExpiresIn when the token is obtained
+	Expiry time.Time `json:"expiry,omitempty"`
+
+	// Time the token was obtained from the provider
+	ObtainedAt time.Time `json:"obtained_at,omitempty"`
+}
+
+// Valid reports whether the token has an access token that is not expired.
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+// expiresWithin reports whether the access token is missing or expires before now plus margin.
+// A token without expiry never expires.
+func (t *Token) expiresWithin(now time.Time, margin time.Duration) bool {
+	return t.AccessToken == "" || (!t.Expiry.IsZero() && !now.Add(margin).Before(t.Expiry))
+}
+
+// UnmarshalJSON accepts expires_in given either as a number or as a string, as some providers send it.
+func (t *Token) UnmarshalJSON(data []byte) error {
+	type token Token
+	var value struct {
+		*token
+		ExpiresIn json.Number `json:"expires_in"`
+	}
+	value.token = (*token)(t)
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value.ExpiresIn != "" {
+		expiresIn, err := value.ExpiresIn.Int64()
+		if err != nil {
+			return err
+		}
+		t.ExpiresIn = expiresIn
+	}
+	return nil
+}
+
+// token posts a grant to a token endpoint and decodes the normalized Token.
+// kind is the sentinel used when the provider rejects the grant.
+func (s *Service) token(ctx context.Context, provider AuthType, endpoint string, style AuthStyle, params url.Values, kind error) (*Token, error) {
+	header, err := s.clientAuth(style, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	err = s.request(endpoint, http.MethodPost,
+		WithTimeout(30*time.Second),
+		WithContentType(ContentTypeWWWForm),
+		WithHeader(header),
+		WithData(params),
+		WithErrorDecoder(providerErrors(provider, kind)),
+	).DoJSON(ctx, &raw)
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{}
+	if len(raw) > 0 {
+		if err = json.Unmarshal(raw, token); err != nil {
+			return nil, err
+		}
+	}
+	if token.AccessToken == "" {
+		// Some providers, e.g. GitHub, report rejected grants with a 200 status.
+		if e := newProviderError(provider, &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, raw, kind); e.Code != "" {
+			return nil, e
+		}
+		return nil, ErrInvalidAccessToken
+	}
+	token.ObtainedAt = time.Now()
+	if token.ExpiresIn > 0 {
+		token.Expiry = token.ObtainedAt.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// clientAuth authenticates the client with style: the returned header carries the Basic credentials,
+// otherwise the client ID and secret are added to params.
+func (s *Service) clientAuth(style AuthStyle, params url.Values) (http.Header, error) {
+	secret := s.ClientSecret
+	if s.secret != nil {
+		var err error
+		if secret, err = s.secret(); err != nil {
+			return nil, err
+		}
+	}
+	header := http.Header{"Accept": []string{"application/json"}}
+	switch style {
+	case AuthStyleBasic:
+		request := &http.Request{Header: http.Header{}}
+		request.SetBasicAuth(url.QueryEscape(s.ClientID), url.QueryEscape(secret))
+		header.Set("Authorization", request.Header.Get("Authorization"))
+	default:
+		params.Set("client_id", s.ClientID)
+		if secret != "" {
+			params.Set("client_secret", secret)
+		}
+	}
+	return header, nil
+}
+
+// refresh posts a refresh_token grant to a token endpoint.
+// Providers that do not rotate refresh tokens omit them from the response, the given one is kept.
+func (s *Service) refresh(ctx context.Context, provider AuthType, endpoint string, style AuthStyle, refreshToken string) (*Token, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	params := url.Values{
+		"grant_type":    []string{"refresh_token"},
+		"refresh_token": []string{refreshToken},
+	}
+	token, err := s.token(ctx, provider, endpoint, style, params, ErrInvalidRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
@@ -0,0 +1,164 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshMargin is how long before expiry a TokenSource refreshes an access token.
+const DefaultRefreshMargin = time.Minute
+
+// DefaultRefreshTimeout is how long a TokenSource waits for a refresh and the save of its token.
+const DefaultRefreshTimeout = 30 * time.Second
+
+// TokenSource returns valid access tokens for the users of a provider, refreshing them proactively
+// shortly before they expire and saving the refreshed tokens, rotated refresh tokens included, in a TokenStore.
+// Concurrent refreshes of the same user are merged into a single call to the provider.
+type TokenSource struct {
+	provider  string
+	refresher Refresher
+	store     TokenStore
+	margin    time.Duration
+	timeout   time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]*refreshCall
+}
+
+// refreshCall is a refresh in progress, shared by the callers of the same user.
+type refreshCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+type TokenSourceOption func(*TokenSource)
+
+// WithRefreshMargin sets how long before expiry the access tokens are refreshed.
+func WithRefreshMargin(margin time.Duration) TokenSourceOption {
+	return func(s *TokenSource) {
+		s.margin = margin
+	}
+}
+
+// WithRefreshTimeout sets how long a refresh and the save of its token may take.
+func WithRefreshTimeout(timeout time.Duration) TokenSourceOption {
+	return func(s *TokenSource) {
+		s.timeout = timeout
+	}
+}
+
+// NewTokenSource creates a TokenSource for the tokens stored under the provider name.
+func NewTokenSource(provider string, refresher Refresher, store TokenStore, options ...TokenSourceOption) *TokenSource {
+	s := &TokenSource{
+		provider:  provider,
+		refresher: refresher,
+		store:     store,
+		margin:    DefaultRefreshMargin,
+		timeout:   DefaultRefreshTimeout,
+		now:       time.Now,
+		calls:     map[string]*refreshCall{},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Save stores the token obtained for the user, e.g. by the code exchange of the login callback.
+func (s *TokenSource) Save(ctx context.Context, userID string, token *Token) error {
+	return s.store.Save(ctx, userID, s.provider, token)
+}
+
+// Token returns the stored token of the user, refreshed first if it expires within the refresh margin.
+func (s *TokenSource) Token(ctx context.Context, userID string) (*Token, error) {
+	token, err := s.store.Load(ctx, userID, s.provider)
+	if err != nil {
+		return nil, err
+	}
+	if !token.expiresWithin(s.now(), s.margin) {
+		return token, nil
+	}
+	return s.do(ctx, userID, false)
+}
+
+// Refresh refreshes the token of the user now, joining a refresh of the same user already in progress.
+func (s *TokenSource) Refresh(ctx context.Context, userID string) (*Token, error) {
+	return s.do(ctx, userID, true)
+}
+
+// do starts or joins the refresh call of the user and waits for its result.
+// The call is detached from the cancellation of the caller that started it, so that the other callers
+// still get its result and a refresh token rotated by the provider is always saved; ctx only bounds the wait.
+func (s *TokenSource) do(ctx context.Context, userID string, force bool) (*Token, error) {
+	s.mu.Lock()
+	call, ok := s.calls[userID]
+	if !ok {
+		call = &refreshCall{done: make(chan struct{})}
+		s.calls[userID] = call
+		refreshCtx, cancel := context.WithTimeout(detachedContext{ctx}, s.timeout)
+		go func() {
+			defer cancel()
+			s.refresh(refreshCtx, userID, force, call)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh runs a refresh call and saves the refreshed token.
+func (s *TokenSource) refresh(ctx context.Context, userID string, force bool, call *refreshCall) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.calls, userID)
+		s.mu.Unlock()
+		close(call.done)
+	}()
+	// Another instance may have refreshed the token since it was loaded.
+	stored, err := s.store.Load(ctx, userID, s.provider)
+	if err != nil {
+		call.err = err
+		return
+	}
+	if !force && !stored.expiresWithin(s.now(), s.margin) {
+		call.token = stored
+		return
+	}
+	token, err := s.refresher.Refresh(ctx, stored.RefreshToken)
+	if err != nil {
+		call.err = err
+		return
+	}
+	if call.err = s.store.Save(ctx, userID, s.provider, token); call.err == nil {
+		call.token = token
+	}
+}
+
+// detachedContext keeps the values of its parent, e.g. for tracing, without its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
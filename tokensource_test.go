@@ -0,0 +1,115 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingRefresher rotates the refresh token on every call and counts the calls.
+type countingRefresher struct {
+	calls int32
+}
+
+func (r *countingRefresher) Refresh(_ context.Context, refreshToken string) (*Token, error) {
+	atomic.AddInt32(&r.calls, 1)
+	time.Sleep(10 * time.Millisecond)
+	now := time.Now()
+	return &Token{AccessToken: "access-" + refreshToken, RefreshToken: refreshToken + "+", ObtainedAt: now, Expiry: now.Add(time.Hour)}, nil
+}
+
+// blockingRefresher refreshes once release is closed, failing if its context is done first.
+type blockingRefresher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRefresher) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	close(r.started)
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	now := time.Now()
+	return &Token{AccessToken: "access-" + refreshToken, RefreshToken: refreshToken + "+", ObtainedAt: now, Expiry: now.Add(time.Hour)}, nil
+}
+
+func TestTokenSourceCanceledCaller(t *testing.T) {
+	store := NewMemoryTokenStore()
+	refresher := &blockingRefresher{started: make(chan struct{}), release: make(chan struct{})}
+	source := NewTokenSource("Microsoft", refresher, store)
+	_ = source.Save(context.Background(), "alice", &Token{AccessToken: "access", RefreshToken: "r", Expiry: time.Now()})
+
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := source.Token(first, "alice")
+		errs <- err
+	}()
+	<-refresher.started
+	second := make(chan *Token, 1)
+	go func() {
+		token, err := source.Token(context.Background(), "alice")
+		if nil != err {
+			t.Errorf("unexpected error of the waiting caller: %v", err)
+		}
+		second <- token
+	}()
+
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("expected the canceled caller to stop waiting, got %v", err)
+	}
+	close(refresher.release)
+	if token := <-second; token == nil || token.AccessToken != "access-r" {
+		t.Fatalf("unexpected token of the waiting caller %+v", token)
+	}
+	stored, _ := store.Load(context.Background(), "alice", "Microsoft")
+	if stored.RefreshToken != "r+" {
+		t.Fatalf("expected the rotated refresh token to be saved, got %+v", stored)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+	refresher := &countingRefresher{}
+	source := NewTokenSource("Google", refresher, store, WithRefreshMargin(5*time.Minute))
+
+	_ = source.Save(ctx, "alice", &Token{AccessToken: "access", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)})
+	token, err := source.Token(ctx, "alice")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access" || refresher.calls != 0 {
+		t.Fatalf("expected the valid token without refresh, got %+v", token)
+	}
+
+	// The token expires within the margin: concurrent callers share a single refresh.
+	_ = source.Save(ctx, "alice", &Token{AccessToken: "access", RefreshToken: "r", Expiry: time.Now().Add(time.Minute)})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := source.Token(ctx, "alice"); nil != err || token.AccessToken != "access-r" {
+				t.Errorf("unexpected token %+v %v", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if refresher.calls != 1 {
+		t.Fatalf("expected a single refresh, got %d", refresher.calls)
+	}
+	stored, _ := store.Load(ctx, "alice", "Google")
+	if stored.RefreshToken != "r+" {
+		t.Fatalf("expected the rotated refresh token to be saved, got %+v", stored)
+	}
+
+	if token, err = source.Refresh(ctx, "alice"); nil != err || token.AccessToken != "access-r+" || refresher.calls != 2 {
+		t.Fatalf("expected a forced refresh, got %+v %v", token, err)
+	}
+}
@@ -0,0 +1,293 @@
+package oauth
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrUnknownKeyID  = errors.New("unknown key id")
+)
+
+// TokenStore persists the tokens of a user at a provider, e.g. refresh tokens for offline access.
+type TokenStore interface {
+	// Save stores the token of the user at the provider, replacing any previous token.
+	Save(ctx context.Context, userID, provider string, token *Token) error
+
+	// Load returns the token of the user at the provider, or ErrTokenNotFound.
+	Load(ctx context.Context, userID, provider string) (*Token, error)
+
+	// Delete removes the token of the user at the provider. Deleting a missing token is not an error.
+	Delete(ctx context.Context, userID, provider string) error
+}
+
+// Refresher is a provider that can refresh tokens: Apple, Google, Line, Microsoft, Kakao, OIDC and OAuth2.
+type Refresher interface {
+	Refresh(ctx context.Context, refreshToken string) (*Token, error)
+}
+
+var (
+	_ Refresher = (*Apple)(nil)
+	_ Refresher = (*Google)(nil)
+	_ Refresher = (*Line)(nil)
+	_ Refresher = (*Microsoft)(nil)
+	_ Refresher = (*Kakao)(nil)
+	_ Refresher = (*OIDC)(nil)
+	_ Refresher = (*OAuth2)(nil)
+)
+
+// RefreshStoredToken refreshes the stored token of the user at the provider and saves the new token,
+// including the rotated refresh token when the provider returns one.
+func RefreshStoredToken(ctx context.Context, store TokenStore, refresher Refresher, userID, provider string) (*Token, error) {
+	stored, err := store.Load(ctx, userID, provider)
+	if err != nil {
+		return nil, err
+	}
+	token, err := refresher.Refresh(ctx, stored.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = store.Save(ctx, userID, provider, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+type tokenKey struct {
+	userID   string
+	provider string
+}
+
+// MemoryTokenStore is a TokenStore kept in the memory of the process, for tests and single instance deployments.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[tokenKey]Token
+}
+
+// NewMemoryTokenStore creates an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[tokenKey]Token{}}
+}
+
+// Save implements TokenStore.
+func (s *MemoryTokenStore) Save(_ context.Context, userID, provider string, token *Token) error {
+	if token == nil {
+		return ErrInvalidAccessToken
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[tokenKey{userID, provider}] = *token
+	return nil
+}
+
+// Load implements TokenStore.
+func (s *MemoryTokenStore) Load(_ context.Context, userID, provider string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[tokenKey{userID, provider}]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+// Delete implements TokenStore.
+func (s *MemoryTokenStore) Delete(_ context.Context, userID, provider string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, tokenKey{userID, provider})
+	return nil
+}
+
+// BlobStore is the storage behind an EncryptedTokenStore, e.g. a table, a key-value store or files.
+// It only ever sees encrypted records.
+type BlobStore interface {
+	// Put stores the record of the user at the provider.
+	Put(ctx context.Context, userID, provider string, record []byte) error
+
+	// Get returns the record of the user at the provider, or ErrTokenNotFound.
+	Get(ctx context.Context, userID, provider string) ([]byte, error)
+
+	// Delete removes the record of the user at the provider.
+	Delete(ctx context.Context, userID, provider string) error
+}
+
+// KeyWrapper encrypts the data keys of an EncryptedTokenStore with key encryption keys identified by an ID,
+// e.g. an AESKeyRing or a KMS.
+type KeyWrapper interface {
+	// Wrap encrypts a data key with the current key encryption key.
+	Wrap(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+
+	// Unwrap decrypts a data key with the key encryption key keyID, or returns ErrUnknownKeyID.
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+
+	// KeyID returns the ID of the current key encryption key.
+	KeyID() string
+}
+
+// AESKeyRing is a KeyWrapper with local AES-GCM keys. Keys are rotated by adding a new key and making it
+// current: records wrapped with older keys stay readable and are re-wrapped by EncryptedTokenStore.Rewrap.
+type AESKeyRing struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewAESKeyRing creates an AESKeyRing from keys of 16, 24 or 32 bytes by ID, current being the ID used to wrap.
+func NewAESKeyRing(current string, keys map[string][]byte) (*AESKeyRing, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, current)
+	}
+	ring := &AESKeyRing{current: current, aeads: map[string]cipher.AEAD{}}
+	for id, key := range keys {
+		aeads, err := newAEADs([][]byte{key})
+		if err != nil {
+			return nil, err
+		}
+		ring.aeads[id] = aeads[0]
+	}
+	return ring, nil
+}
+
+// KeyID implements KeyWrapper.
+func (r *AESKeyRing) KeyID() string {
+	return r.current
+}
+
+// Wrap implements KeyWrapper.
+func (r *AESKeyRing) Wrap(_ context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(r.aeads[r.current], dataKey, []byte(r.current))
+	if err != nil {
+		return "", nil, err
+	}
+	return r.current, []byte(wrapped), nil
+}
+
+// Unwrap implements KeyWrapper.
+func (r *AESKeyRing) Unwrap(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := r.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, keyID)
+	}
+	return unseal([]cipher.AEAD{aead}, string(wrapped), []byte(keyID))
+}
+
+// encryptedToken is the record stored by an EncryptedTokenStore.
+type encryptedToken struct {
+	// KeyID ID of the key encryption key that wrapped DataKey
+	KeyID string `json:"kid"`
+
+	// DataKey Wrapped AES-256 key of this record
+	DataKey []byte `json:"dek"`
+
+	// Data Token encrypted with the data key, bound to the user and provider
+	Data string `json:"data"`
+}
+
+// EncryptedTokenStore is a TokenStore encrypting tokens at rest with envelope encryption: each token is
+// encrypted with its own AES-256-GCM data key, itself wrapped by the KeyWrapper. The encrypted record is bound
+// to its user and provider, so records swapped in the BlobStore are rejected.
+type EncryptedTokenStore struct {
+	blobs BlobStore
+	keys  KeyWrapper
+}
+
+// NewEncryptedTokenStore creates an EncryptedTokenStore saving its records in blobs.
+func NewEncryptedTokenStore(blobs BlobStore, keys KeyWrapper) *EncryptedTokenStore {
+	return &EncryptedTokenStore{blobs: blobs, keys: keys}
+}
+
+// Save implements TokenStore.
+func (s *EncryptedTokenStore) Save(ctx context.Context, userID, provider string, token *Token) error {
+	if token == nil {
+		return ErrInvalidAccessToken
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	dataKey := make([]byte, 32)
+	if _, err = rand.Read(dataKey); err != nil {
+		return err
+	}
+	aeads, err := newAEADs([][]byte{dataKey})
+	if err != nil {
+		return err
+	}
+	record := &encryptedToken{}
+	if record.Data, err = seal(aeads[0], data, recordBinding(userID, provider)); err != nil {
+		return err
+	}
+	if record.KeyID, record.DataKey, err = s.keys.Wrap(ctx, dataKey); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.blobs.Put(ctx, userID, provider, encoded)
+}
+
+// Load implements TokenStore. Records wrapped with a key that is no longer current stay readable.
+func (s *EncryptedTokenStore) Load(ctx context.Context, userID, provider string) (*Token, error) {
+	token, _, err := s.load(ctx, userID, provider)
+	return token, err
+}
+
+// Rewrap saves the token of the user again if its record is wrapped with a key that is no longer current,
+// and reports whether it did. It writes the token it read: run it where the token of the user is not
+// being refreshed at the same time, e.g. in a maintenance job after a key rotation.
+func (s *EncryptedTokenStore) Rewrap(ctx context.Context, userID, provider string) (bool, error) {
+	token, keyID, err := s.load(ctx, userID, provider)
+	if err != nil || keyID == s.keys.KeyID() {
+		return false, err
+	}
+	if err = s.Save(ctx, userID, provider, token); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// load decrypts the token of the user and returns the ID of the key its record is wrapped with.
+func (s *EncryptedTokenStore) load(ctx context.Context, userID, provider string) (*Token, string, error) {
+	encoded, err := s.blobs.Get(ctx, userID, provider)
+	if err != nil {
+		return nil, "", err
+	}
+	record := &encryptedToken{}
+	if err = json.Unmarshal(encoded, record); err != nil {
+		return nil, "", err
+	}
+	dataKey, err := s.keys.Unwrap(ctx, record.KeyID, record.DataKey)
+	if err != nil {
+		return nil, "", err
+	}
+	aeads, err := newAEADs([][]byte{dataKey})
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := unseal(aeads, record.Data, recordBinding(userID, provider))
+	if err != nil {
+		return nil, "", err
+	}
+	token := &Token{}
+	if err = json.Unmarshal(data, token); err != nil {
+		return nil, "", err
+	}
+	return token, record.KeyID, nil
+}
+
+// Delete implements TokenStore.
+func (s *EncryptedTokenStore) Delete(ctx context.Context, userID, provider string) error {
+	return s.blobs.Delete(ctx, userID, provider)
+}
+
+// recordBinding is the additional data binding an encrypted token to its user and provider.
+func recordBinding(userID, provider string) []byte {
+	return []byte(provider + "\x00" + userID)
+}
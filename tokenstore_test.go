@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// memoryBlobStore is a BlobStore backed by a map, exposing the records for inspection.
+type memoryBlobStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+}
+
+func (s *memoryBlobStore) Put(_ context.Context, userID, provider string, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[provider+"/"+userID] = record
+	return nil
+}
+
+func (s *memoryBlobStore) Get(_ context.Context, userID, provider string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[provider+"/"+userID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return record, nil
+}
+
+func (s *memoryBlobStore) Delete(_ context.Context, userID, provider string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, provider+"/"+userID)
+	return nil
+}
+
+func TestEncryptedTokenStore(t *testing.T) {
+	ctx := context.Background()
+	blobs := &memoryBlobStore{records: map[string][]byte{}}
+	oldRing, err := NewAESKeyRing("2023", map[string][]byte{"2023": bytes.Repeat([]byte{1}, 32)})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err = NewEncryptedTokenStore(blobs, oldRing).Save(ctx, "alice", "Google", &Token{AccessToken: "access", RefreshToken: "refresh-secret"}); nil != err {
+		t.Fatal(err)
+	}
+	if bytes.Contains(blobs.records["Google/alice"], []byte("refresh-secret")) {
+		t.Fatal("expected the token to be encrypted at rest")
+	}
+
+	ring, _ := NewAESKeyRing("2024", map[string][]byte{"2023": bytes.Repeat([]byte{1}, 32), "2024": bytes.Repeat([]byte{2}, 32)})
+	store := NewEncryptedTokenStore(blobs, ring)
+	token, err := store.Load(ctx, "alice", "Google")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if token.RefreshToken != "refresh-secret" || !bytes.Contains(blobs.records["Google/alice"], []byte(`"kid":"2023"`)) {
+		t.Fatalf("expected Load to leave the record unchanged, got %s", blobs.records["Google/alice"])
+	}
+	if rewrapped, err := store.Rewrap(ctx, "alice", "Google"); nil != err || !rewrapped {
+		t.Fatalf("expected the record to be re-wrapped: %v", err)
+	}
+	if !bytes.Contains(blobs.records["Google/alice"], []byte(`"kid":"2024"`)) {
+		t.Fatalf("expected the record to be re-wrapped with the current key, got %s", blobs.records["Google/alice"])
+	}
+	if rewrapped, err := store.Rewrap(ctx, "alice", "Google"); nil != err || rewrapped {
+		t.Fatalf("expected a current record to be kept: %v", err)
+	}
+
+	blobs.records["Google/mallory"] = blobs.records["Google/alice"]
+	if _, err = store.Load(ctx, "mallory", "Google"); nil == err {
+		t.Fatal("expected a record of another user to be rejected")
+	}
+	_ = store.Delete(ctx, "alice", "Google")
+	if _, err = store.Load(ctx, "alice", "Google"); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestRefreshStoredToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != "refresh_token" || r.PostFormValue("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"new-access","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	service, _ := NewService("client", "secret", AuthLine, WithTransport(rewriteTransport{server.URL}))
+	store := NewMemoryTokenStore()
+	ctx := context.Background()
+	_ = store.Save(ctx, "alice", "Line", &Token{AccessToken: "access", RefreshToken: "refresh"})
+	if _, err := RefreshStoredToken(ctx, store, NewLine(service), "alice", "Line"); nil != err {
+		t.Fatal(err)
+	}
+	token, err := store.Load(ctx, "alice", "Line")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "new-access" || token.RefreshToken != "refresh" || token.Expiry.IsZero() {
+		t.Fatalf("unexpected stored token %+v", token)
+	}
+}